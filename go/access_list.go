@@ -0,0 +1,100 @@
+package evm
+
+// AccessTuple is an entry of a transaction access list (EIP-2930): an
+// address and the storage slots of it the transaction declares.
+type AccessTuple struct {
+	Address     Address
+	StorageKeys []Hash
+}
+
+// AccessList is the access list of a transaction. Its addresses and slots
+// are warm from the start of the transaction.
+type AccessList []AccessTuple
+
+// StorageKeys returns the number of storage keys in the list.
+func (al AccessList) StorageKeys() int {
+	sum := 0
+	for _, tuple := range al {
+		sum += len(tuple.StorageKeys)
+	}
+	return sum
+}
+
+// accessList is the set of addresses and storage slots accessed by a
+// transaction (EIP-2929). An address is in the set whenever one of its
+// slots is.
+type accessList struct {
+	addresses map[Address]int // index into slots, or -1 if there are none
+	slots     []map[Hash]struct{}
+}
+
+func newAccessList() *accessList {
+	return &accessList{addresses: make(map[Address]int)}
+}
+
+func (al *accessList) ContainsAddress(address Address) bool {
+	_, ok := al.addresses[address]
+	return ok
+}
+
+// Contains reports whether address, and the slot of it, are in the list.
+func (al *accessList) Contains(address Address, slot Hash) (addressPresent bool, slotPresent bool) {
+	idx, ok := al.addresses[address]
+	if !ok {
+		return false, false
+	}
+	if idx == -1 {
+		return true, false
+	}
+	_, slotPresent = al.slots[idx][slot]
+	return true, slotPresent
+}
+
+// AddAddress adds address to the list and reports whether it was missing.
+func (al *accessList) AddAddress(address Address) bool {
+	if _, present := al.addresses[address]; present {
+		return false
+	}
+	al.addresses[address] = -1
+	return true
+}
+
+// AddSlot adds the slot of address to the list, adding address if needed,
+// and reports which of the two were missing.
+func (al *accessList) AddSlot(address Address, slot Hash) (addrChange bool, slotChange bool) {
+	idx, addrPresent := al.addresses[address]
+	if !addrPresent || idx == -1 {
+		al.addresses[address] = len(al.slots)
+		al.slots = append(al.slots, map[Hash]struct{}{slot: {}})
+		return !addrPresent, true
+	}
+	if _, ok := al.slots[idx][slot]; ok {
+		return false, false
+	}
+	al.slots[idx][slot] = struct{}{}
+	return false, true
+}
+
+// DeleteSlot removes a slot added by AddSlot. It undoes the most recent
+// addition of a slot to address, so it must be called in reverse order of
+// the additions, as the journal does.
+func (al *accessList) DeleteSlot(address Address, slot Hash) {
+	idx, ok := al.addresses[address]
+	if !ok || idx == -1 {
+		panic("reverting slot change, address not present in list")
+	}
+	slotmap := al.slots[idx]
+	delete(slotmap, slot)
+	// the slot map was created with this slot, so the address had none
+	// before it
+	if len(slotmap) == 0 {
+		al.slots = al.slots[:idx]
+		al.addresses[address] = -1
+	}
+}
+
+// DeleteAddress removes an address added by AddAddress, which must not have
+// any slots left.
+func (al *accessList) DeleteAddress(address Address) {
+	delete(al.addresses, address)
+}
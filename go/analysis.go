@@ -0,0 +1,79 @@
+package evm
+
+import "sync"
+
+// CodeBitmap marks which bytes of a program are PUSH data. Bit i is set
+// when code[i] is an immediate of a preceding PUSH rather than an opcode.
+type CodeBitmap []byte
+
+// IsCode reports whether the byte at pos is an opcode. Positions past the
+// end of the analysed code count as code.
+func (bits CodeBitmap) IsCode(pos uint64) bool {
+	if pos/8 >= uint64(len(bits)) {
+		return true
+	}
+	return bits[pos/8]&(1<<(pos%8)) == 0
+}
+
+func (bits CodeBitmap) setData(pos uint64) {
+	bits[pos/8] |= 1 << (pos % 8)
+}
+
+// codeBitmap walks code once and marks the data bytes of every PUSH.
+func codeBitmap(code []byte) CodeBitmap {
+	// the extra 4 bytes cover a PUSH32 starting at the last byte
+	bits := make(CodeBitmap, len(code)/8+1+4)
+	for pc := uint64(0); pc < uint64(len(code)); {
+		op := OpCode(code[pc])
+		pc++
+		if !op.IsPush() {
+			continue
+		}
+		numbytes := uint64(op-PUSH1) + 1
+		for i := uint64(0); i < numbytes; i++ {
+			bits.setData(pc + i)
+		}
+		pc += numbytes
+	}
+	return bits
+}
+
+// analysisCacheLimit bounds the number of cached bitmaps. When the cache is
+// full an arbitrary entry is evicted.
+const analysisCacheLimit = 4096
+
+var analysisCache = struct {
+	sync.Mutex
+	entries map[Hash]CodeBitmap
+}{entries: make(map[Hash]CodeBitmap)}
+
+// Analyze returns the bitmap of code. Results are cached by code hash, so
+// code that runs again, in the same or another EVM, is only walked once.
+// The returned bitmap is shared and must not be modified. Analyze hashes
+// code on every call; frames use the code hash of the account instead.
+func Analyze(code []byte) CodeBitmap {
+	return analyzeWithHash(Keccak256Hash(code), code)
+}
+
+// analyzeWithHash is Analyze for code whose hash is already known.
+func analyzeWithHash(codeHash Hash, code []byte) CodeBitmap {
+	analysisCache.Lock()
+	bits, ok := analysisCache.entries[codeHash]
+	analysisCache.Unlock()
+	if ok {
+		return bits
+	}
+
+	bits = codeBitmap(code)
+
+	analysisCache.Lock()
+	defer analysisCache.Unlock()
+	if len(analysisCache.entries) >= analysisCacheLimit {
+		for h := range analysisCache.entries {
+			delete(analysisCache.entries, h)
+			break
+		}
+	}
+	analysisCache.entries[codeHash] = bits
+	return bits
+}
@@ -0,0 +1,76 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestCodeBitmap(t *testing.T) {
+	// PUSH2 0x5b5b, JUMPDEST, PUSH32 ... (truncated)
+	code := []byte{byte(PUSH2), 0x5b, 0x5b, byte(JUMPDEST), byte(PUSH32), 0x5b}
+	bits := codeBitmap(code)
+	for pos, want := range []bool{true, false, false, true, true, false} {
+		if got := bits.IsCode(uint64(pos)); got != want {
+			t.Errorf("IsCode(%d) = %v, want %v", pos, got, want)
+		}
+	}
+}
+
+func TestAnalyzeCache(t *testing.T) {
+	code := []byte{byte(PUSH1), 0x00, byte(JUMPDEST)}
+	a, b := Analyze(code), Analyze(append([]byte{}, code...))
+	if &a[0] != &b[0] {
+		t.Error("identical code was analysed twice")
+	}
+}
+
+func TestJumpPastEndOfCode(t *testing.T) {
+	// PUSH1 3, JUMP: the destination equals the code length
+	res := runHex(t, "600356")
+	if res.Success || !errors.Is(res.Err, ErrInvalidJump) {
+		t.Errorf("expected invalid jump, got %v", res.Err)
+	}
+}
+
+func BenchmarkLoop(b *testing.B) {
+	// PUSH2 0xffff, JUMPDEST, PUSH1 1, SWAP1, SUB, DUP1, PUSH1 3, JUMPI
+	code := []byte{0x61, 0xff, 0xff, 0x5b, 0x60, 0x01, 0x90, 0x03, 0x80, 0x60, 0x03, 0x57}
+	evm := NewEVM(BlockContext{}, TxContext{}, nil, Config{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if res := evm.Run(code, testGas); !res.Success {
+			b.Fatal(res.Err)
+		}
+	}
+}
+
+func TestJumpAnalysisUsesCodeHash(t *testing.T) {
+	code := []byte{byte(JUMPDEST), byte(PUSH1), 0x00, byte(JUMP)}
+	db := NewMemoryStateDB()
+	addr := HexToAddress("0xaa")
+	db.SetCode(addr, code)
+	// a bitmap that marks the JUMPDEST as data, cached under the code hash
+	codeHash := db.GetCodeHash(addr)
+	bits := codeBitmap(code)
+	bits.setData(0)
+	analysisCache.Lock()
+	analysisCache.entries[codeHash] = bits
+	analysisCache.Unlock()
+	defer func() {
+		analysisCache.Lock()
+		delete(analysisCache.entries, codeHash)
+		analysisCache.Unlock()
+	}()
+
+	contract := NewContract(Address{}, addr, nil, code, nil, testGas)
+	contract.CodeHash = codeHash
+	if contract.validJumpdest(new(uint256.Int)) {
+		t.Error("the cached analysis of the code hash was not used")
+	}
+	// init code has no hash and is analysed directly
+	if !NewContract(Address{}, addr, nil, code, nil, testGas).validJumpdest(new(uint256.Int)) {
+		t.Error("init code analysis went through the cache")
+	}
+}
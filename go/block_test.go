@@ -0,0 +1,51 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestBlockhashWindow(t *testing.T) {
+	ctx := BlockContext{
+		Number: 1000,
+		GetHash: func(n uint64) Hash {
+			return BytesToHash(new(uint256.Int).SetUint64(n + 1).Bytes())
+		},
+	}
+	tests := []struct {
+		number uint64
+		want   uint64
+	}{
+		{number: 999, want: 1000},
+		{number: 744, want: 745},
+		{number: 743, want: 0},  // older than 256 blocks
+		{number: 1000, want: 0}, // the current block
+		{number: 1001, want: 0},
+	}
+	for _, tt := range tests {
+		code := append([]byte{byte(PUSH2), byte(tt.number >> 8), byte(tt.number)}, byte(BLOCKHASH))
+		res := NewEVM(ctx, TxContext{}, nil, Config{}).Run(code, testGas)
+		if !res.Success || res.Stack[0].Uint64() != tt.want {
+			t.Errorf("BLOCKHASH(%d): got %v %v, want %d", tt.number, res.Stack, res.Err, tt.want)
+		}
+	}
+
+	// without GetHash every block hash is zero
+	res := NewEVM(BlockContext{Number: 1000}, TxContext{}, nil, Config{}).Run([]byte{byte(PUSH2), 0x03, 0xe7, byte(BLOCKHASH)}, testGas)
+	if !res.Success || !res.Stack[0].IsZero() {
+		t.Errorf("BLOCKHASH without GetHash: got %v %v", res.Stack, res.Err)
+	}
+}
+
+func TestBaseFeeFork(t *testing.T) {
+	ctx := BlockContext{BaseFee: uint256.NewInt(7)}
+	code := []byte{byte(BASEFEE)}
+	if res := NewEVM(ctx, TxContext{}, nil, Config{Fork: Berlin}).Run(code, testGas); res.Success {
+		t.Error("BASEFEE succeeded on Berlin")
+	}
+	res := NewEVM(ctx, TxContext{}, nil, Config{Fork: London}).Run(code, testGas)
+	if !res.Success || res.Stack[0].Uint64() != 7 {
+		t.Errorf("BASEFEE on London: got %v %v", res.Stack, res.Err)
+	}
+}
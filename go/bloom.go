@@ -0,0 +1,58 @@
+package evm
+
+const (
+	// BloomByteLength is the length of a logs bloom in bytes.
+	BloomByteLength = 256
+
+	// BloomBitLength is the length of a logs bloom in bits.
+	BloomBitLength = 8 * BloomByteLength
+)
+
+// Bloom is the 2048-bit bloom filter over the addresses and topics of a set
+// of logs, as found in receipts and block headers.
+type Bloom [BloomByteLength]byte
+
+// CreateBloom returns the bloom of the addresses and topics of logs.
+func CreateBloom(logs []*Log) Bloom {
+	var b Bloom
+	for _, log := range logs {
+		b.Add(log.Address.Bytes())
+		for _, topic := range log.Topics {
+			b.Add(topic.Bytes())
+		}
+	}
+	return b
+}
+
+// Add sets the three bits selected by the keccak256 digest of d.
+func (b *Bloom) Add(d []byte) {
+	i1, v1, i2, v2, i3, v3 := bloomValues(d)
+	b[i1] |= v1
+	b[i2] |= v2
+	b[i3] |= v3
+}
+
+// Test reports whether d may have been added to the bloom.
+func (b Bloom) Test(d []byte) bool {
+	i1, v1, i2, v2, i3, v3 := bloomValues(d)
+	return v1 == v1&b[i1] &&
+		v2 == v2&b[i2] &&
+		v3 == v3&b[i3]
+}
+
+func (b Bloom) Bytes() []byte { return b[:] }
+
+// bloomValues returns the byte indexes and bit masks of the three bits that
+// represent d. Each bit is chosen by the low 11 bits of one of the first
+// three pairs of bytes of the keccak256 digest of d.
+func bloomValues(d []byte) (uint, byte, uint, byte, uint, byte) {
+	hash := Keccak256(d)
+	v1 := byte(1 << (hash[1] & 0x7))
+	v2 := byte(1 << (hash[3] & 0x7))
+	v3 := byte(1 << (hash[5] & 0x7))
+	// the bloom is big-endian: bit 0 is the lowest bit of the last byte
+	i1 := BloomByteLength - ((uint(hash[0])<<8|uint(hash[1]))&2047)>>3 - 1
+	i2 := BloomByteLength - ((uint(hash[2])<<8|uint(hash[3]))&2047)>>3 - 1
+	i3 := BloomByteLength - ((uint(hash[4])<<8|uint(hash[5]))&2047)>>3 - 1
+	return i1, v1, i2, v2, i3, v3
+}
@@ -0,0 +1,134 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	var (
+		db   = NewMemoryStateDB()
+		self = HexToAddress("0xaa")
+	)
+	// increment slot 0, then call itself
+	db.SetCode(self, mustDecode(t, "600054600101600055"+"60008080808030"+"6000f1"))
+	// without metering, since the gas forwarded to each frame shrinks
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{NoGasMetering: true})
+	if _, _, err := evm.Call(Address{}, self, nil, testGas, new(uint256.Int)); err != nil {
+		t.Fatal(err)
+	}
+	// the outermost frame runs at depth 1, and calls from depth 1025 fail
+	if got := db.GetState(self, Hash{}); got != BytesToHash([]byte{0x04, 0x01}) {
+		t.Errorf("frames executed = %v, want 1025", got)
+	}
+}
+
+func TestCallValueTransfer(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		caller = HexToAddress("0xc0")
+		to     = HexToAddress("0xde")
+	)
+	db.AddBalance(caller, uint256.NewInt(10))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	if _, _, err := evm.Call(caller, to, nil, testGas, uint256.NewInt(11)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
+	}
+	if db.Exist(to) {
+		t.Error("failed transfer created the recipient")
+	}
+	if _, _, err := evm.Call(caller, to, nil, testGas, uint256.NewInt(4)); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetBalance(caller).Uint64() != 6 || db.GetBalance(to).Uint64() != 4 {
+		t.Errorf("balances %v %v, want 6 4", db.GetBalance(caller), db.GetBalance(to))
+	}
+
+	// a reverting recipient gets its value back
+	db.SetCode(to, mustDecode(t, "60006000fd"))
+	if _, _, err := evm.Call(caller, to, nil, testGas, uint256.NewInt(1)); !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("expected a revert, got %v", err)
+	}
+	if db.GetBalance(caller).Uint64() != 6 || db.GetBalance(to).Uint64() != 4 {
+		t.Errorf("balances %v %v after revert, want 6 4", db.GetBalance(caller), db.GetBalance(to))
+	}
+}
+
+func TestCallContexts(t *testing.T) {
+	var (
+		db      = NewMemoryStateDB()
+		origin  = HexToAddress("0x01")
+		proxy   = HexToAddress("0xaa")
+		library = HexToAddress("0xbb")
+	)
+	// the library stores CALLER at slot 0 and CALLVALUE at slot 1
+	db.SetCode(library, mustDecode(t, "336000553460015500"))
+	tests := []struct {
+		name       string
+		op         OpCode
+		wantCaller Address
+		wantValue  uint64
+	}{
+		// value 0, since the proxy's CALLCODE passes none
+		{"CALLCODE", CALLCODE, proxy, 0},
+		// the sender and value of the call to the proxy
+		{"DELEGATECALL", DELEGATECALL, origin, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "6000808080"
+			if tt.op == CALLCODE {
+				code += "80" // value
+			}
+			code += "73" + hex.EncodeToString(library.Bytes()) + "5a" + hex.EncodeToString([]byte{byte(tt.op)})
+			db.SetCode(proxy, mustDecode(t, code))
+			db.AddBalance(origin, uint256.NewInt(7))
+
+			evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+			if _, _, err := evm.Call(origin, proxy, nil, testGas, uint256.NewInt(7)); err != nil {
+				t.Fatal(err)
+			}
+			if got := BytesToAddress(db.GetState(proxy, Hash{}).Bytes()); got != tt.wantCaller {
+				t.Errorf("CALLER = %v, want %v", got, tt.wantCaller)
+			}
+			if got := db.GetState(proxy, HexToHash("0x01")); got != BytesToHash([]byte{byte(tt.wantValue)}) {
+				t.Errorf("CALLVALUE = %v, want %d", got, tt.wantValue)
+			}
+			if db.GetState(library, Hash{}) != (Hash{}) {
+				t.Error("the library's own storage was written")
+			}
+		})
+	}
+}
+
+func TestNilValue(t *testing.T) {
+	db := NewMemoryStateDB()
+	callee := HexToAddress("0xbb")
+	db.SetCode(callee, mustDecode(t, "00"))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+
+	if _, _, err := evm.Call(HexToAddress("0xaa"), callee, nil, testGas, nil); err != nil {
+		t.Errorf("Call: %v", err)
+	}
+	if _, _, err := evm.CallCode(HexToAddress("0xaa"), callee, nil, testGas, nil); err != nil {
+		t.Errorf("CallCode: %v", err)
+	}
+	if _, _, _, err := evm.Create(HexToAddress("0xaa"), nil, testGas, nil); err != nil {
+		t.Errorf("Create: %v", err)
+	}
+	if _, _, _, err := evm.Create2(HexToAddress("0xaa"), nil, testGas, nil, new(uint256.Int)); err != nil {
+		t.Errorf("Create2: %v", err)
+	}
+}
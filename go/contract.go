@@ -0,0 +1,76 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+// Contract is the account whose code a frame executes, together with the
+// call that started the frame.
+type Contract struct {
+	CallerAddress Address
+	Address       Address
+	Value         *uint256.Int
+	Input         []byte
+	Code          []byte
+	// CodeHash is the hash of Code if it is the code of an account, and
+	// zero for init code. It keys the shared jump analysis cache.
+	CodeHash Hash
+	Gas      uint64 // left in the frame
+
+	analysis CodeBitmap // computed on the first jump
+}
+
+// NewContract returns the contract for a frame in which caller runs the code
+// of address with the given input and gas. A nil value is treated as zero.
+func NewContract(caller, address Address, value *uint256.Int, code, input []byte, gas uint64) *Contract {
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	return &Contract{
+		CallerAddress: caller,
+		Address:       address,
+		Value:         value,
+		Input:         input,
+		Code:          code,
+		Gas:           gas,
+	}
+}
+
+// UseGas deducts gas from the frame and reports whether there was enough.
+// If there was not, the gas is left untouched.
+func (c *Contract) UseGas(gas uint64) bool {
+	if c.Gas < gas {
+		return false
+	}
+	c.Gas -= gas
+	return true
+}
+
+// GetOp returns the opcode at n. Running off the end of the code is an
+// implicit STOP.
+func (c *Contract) GetOp(n uint64) OpCode {
+	if n < uint64(len(c.Code)) {
+		return OpCode(c.Code[n])
+	}
+	return STOP
+}
+
+// validJumpdest reports whether dest is a JUMPDEST opcode, as opposed to a
+// 0x5b byte inside PUSH data. The code is analysed on the first jump:
+// through the cache for account code, directly for init code, which has no
+// hash and rarely runs twice.
+func (c *Contract) validJumpdest(dest *uint256.Int) bool {
+	udest, overflow := dest.Uint64WithOverflow()
+	if overflow || udest >= uint64(len(c.Code)) {
+		return false
+	}
+	if OpCode(c.Code[udest]) != JUMPDEST {
+		return false
+	}
+	if c.analysis == nil {
+		if c.CodeHash != (Hash{}) {
+			c.analysis = analyzeWithHash(c.CodeHash, c.Code)
+		} else {
+			c.analysis = codeBitmap(c.Code)
+		}
+	}
+	return c.analysis.IsCode(udest)
+}
@@ -0,0 +1,50 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestCallDataOutOfRange(t *testing.T) {
+	input := []byte{0xaa, 0xbb}
+	tests := []struct {
+		name string
+		code []byte
+		want string
+	}{
+		// CALLDATALOAD at offset 1 pads the word on the right
+		{"load tail", []byte{byte(PUSH1), 1, byte(CALLDATALOAD)}, "0xbb00000000000000000000000000000000000000000000000000000000000000"},
+		// an offset beyond uint64 reads zero
+		{"load huge offset", []byte{byte(PUSH9), 1, 0, 0, 0, 0, 0, 0, 0, 0, byte(CALLDATALOAD)}, "0x0"},
+		// CALLDATACOPY of 4 bytes from offset 1 to memory 0, then MLOAD 0
+		{"copy tail", []byte{byte(PUSH1), 4, byte(PUSH1), 1, byte(PUSH1), 0, byte(CALLDATACOPY), byte(PUSH1), 0, byte(MLOAD)}, "0xbb00000000000000000000000000000000000000000000000000000000000000"},
+		// copying from an offset beyond uint64 writes zeros
+		{"copy huge offset", []byte{byte(PUSH1), 0xff, byte(PUSH1), 0, byte(MSTORE8), byte(PUSH1), 1, byte(PUSH9), 1, 0, 0, 0, 0, 0, 0, 0, 0, byte(PUSH1), 0, byte(CALLDATACOPY), byte(PUSH1), 0, byte(MLOAD)}, "0x0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract := NewContract(Address{}, Address{}, nil, tt.code, input, testGas)
+			res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).RunContract(contract)
+			if !res.Success || len(res.Stack) != 1 || res.Stack[0].Hex() != tt.want {
+				t.Errorf("got %v %v, want %s", res.Stack, res.Err, tt.want)
+			}
+		})
+	}
+}
+
+func TestContractContext(t *testing.T) {
+	caller, self := HexToAddress("0xc0ffee"), HexToAddress("0xbeef")
+	code := []byte{byte(ADDRESS), byte(CALLER), byte(CALLVALUE), byte(ORIGIN)}
+	contract := NewContract(caller, self, uint256.NewInt(5), code, nil, testGas)
+	res := NewEVM(BlockContext{}, TxContext{Origin: HexToAddress("0x0a")}, nil, Config{}).RunContract(contract)
+	want := []uint64{0x0a, 5, 0xc0ffee, 0xbeef}
+	if !res.Success || len(res.Stack) != len(want) {
+		t.Fatalf("got %v %v", res.Stack, res.Err)
+	}
+	for i, w := range want {
+		if res.Stack[i].Uint64() != w {
+			t.Errorf("stack[%d] = %v, want %#x", i, &res.Stack[i], w)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestCreateAddress(t *testing.T) {
+	sender := HexToAddress("0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0")
+	for nonce, want := range []string{
+		"0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d",
+		"0x343c43a37d37dff08ae8c4a11544c718abb4fcf8",
+		"0xf778b86fa74e846c4f0a1fbd1335fe81c00a0c91",
+	} {
+		if got := CreateAddress(sender, uint64(nonce)); got != HexToAddress(want) {
+			t.Errorf("nonce %d: got %v, want %s", nonce, got, want)
+		}
+	}
+}
+
+func TestCreateAddress2(t *testing.T) {
+	// examples from EIP-1014
+	tests := []struct {
+		sender, salt, code, want string
+	}{
+		{"0x00", "0x00", "00", "0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x00", "00", "0xb928f69bb1d91cd65274e3c79d8986362984fda3"},
+		{"0x00", "0x00", "", "0xe33c0c7f7df4809055c3eba6c09cfe4baf1bd9e0"},
+	}
+	for _, tt := range tests {
+		got := CreateAddress2(HexToAddress(tt.sender), HexToHash(tt.salt), Keccak256(fromHex(tt.code)))
+		if got != HexToAddress(tt.want) {
+			t.Errorf("CreateAddress2(%s, %s, %s) = %v, want %s", tt.sender, tt.salt, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		sender = HexToAddress("0xc0")
+		// init code returning the single byte 0x00
+		initCode = fromHex("60016000f3")
+	)
+	db.SetNonce(sender, 5)
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+
+	ret, addr, _, err := evm.Create(sender, initCode, testGas, new(uint256.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != CreateAddress(sender, 5) || db.GetNonce(sender) != 6 {
+		t.Errorf("created %v with sender nonce %d", addr, db.GetNonce(sender))
+	}
+	if len(ret) != 1 || db.GetCodeSize(addr) != 1 || db.GetNonce(addr) != 1 {
+		t.Errorf("unexpected contract: code %x, nonce %d", db.GetCode(addr), db.GetNonce(addr))
+	}
+
+	// a second CREATE2 with the same salt and code collides, but still
+	// spends the nonce
+	salt := uint256.NewInt(1)
+	if _, _, _, err := evm.Create2(sender, initCode, testGas, new(uint256.Int), salt); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := evm.Create2(sender, initCode, testGas, new(uint256.Int), salt); !errors.Is(err, ErrContractAddressCollision) {
+		t.Errorf("expected a collision, got %v", err)
+	}
+	if db.GetNonce(sender) != 8 {
+		t.Errorf("sender nonce = %d, want 8", db.GetNonce(sender))
+	}
+
+	// deployed code must not start with 0xEF from London on
+	efCode := fromHex("60ef60005360016000f3")
+	if _, addr, _, err := evm.Create(sender, efCode, testGas, new(uint256.Int)); !errors.Is(err, ErrInvalidCode) || db.Exist(addr) {
+		t.Errorf("expected ErrInvalidCode, got %v", err)
+	}
+	berlin := NewEVM(BlockContext{}, TxContext{}, db, Config{Fork: Berlin})
+	if _, _, _, err := berlin.Create(sender, efCode, testGas, new(uint256.Int)); err != nil {
+		t.Errorf("0xEF code rejected on Berlin: %v", err)
+	}
+}
+
+func TestCreateOpcodes(t *testing.T) {
+	self := HexToAddress("0xc0")
+	// MSTORE the init code at 0 then CREATE2 it with salt 0x2a
+	initCode := "60016000f3"
+	code := fromHex("64" + initCode + "600052" + "602a" + "6005" + "601b" + "6000" + "f5")
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).RunContract(NewContract(Address{}, self, nil, code, nil, testGas))
+	want := CreateAddress2(self, uint256.NewInt(0x2a).Bytes32(), Keccak256(fromHex(initCode)))
+	if !res.Success || BytesToAddress(res.Stack[0].Bytes()) != want {
+		t.Errorf("CREATE2 pushed %v (%v), want %v", res.Stack, res.Err, want)
+	}
+
+	// init code above the EIP-3860 limit fails the creating frame
+	code = fromHex("62" + "00c001" + "6000" + "6000" + "f0")
+	res = NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(code, testGas)
+	if res.Success || !errors.Is(res.Err, ErrMaxInitCodeSizeExceeded) {
+		t.Errorf("expected ErrMaxInitCodeSizeExceeded, got %v", res.Err)
+	}
+}
@@ -0,0 +1,103 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that stop execution of a frame. They are reported through
+// ExecutionResult.Err, wrapped in an ExecutionError that records where
+// execution stopped.
+var (
+	ErrOutOfGas              = errors.New("out of gas")
+	ErrDepth                 = errors.New("max call depth exceeded")
+	ErrInsufficientBalance   = errors.New("insufficient balance for transfer")
+	ErrExecutionReverted     = errors.New("execution reverted")
+	ErrInvalidJump           = errors.New("invalid jump destination")
+	ErrWriteProtection       = errors.New("write protection")
+	ErrReturnDataOutOfBounds = errors.New("return data out of bounds")
+	ErrGasUintOverflow       = errors.New("gas uint64 overflow")
+	ErrMemoryLimit           = errors.New("memory limit exceeded")
+
+	ErrCodeStoreOutOfGas        = errors.New("contract creation code storage out of gas")
+	ErrContractAddressCollision = errors.New("contract address collision")
+	ErrMaxCodeSizeExceeded      = errors.New("max code size exceeded")
+	ErrMaxInitCodeSizeExceeded  = errors.New("max initcode size exceeded")
+	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
+	ErrNonceUintOverflow        = errors.New("nonce uint64 overflow")
+)
+
+// Errors that make a transaction invalid. ApplyMessage returns them before
+// executing anything, and the transaction cannot be included in a block.
+var (
+	ErrIntrinsicGas      = errors.New("intrinsic gas too low")
+	ErrFloorDataGas      = errors.New("insufficient gas for floor data gas cost")
+	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
+	ErrFeeCapTooLow      = errors.New("gas price less than block base fee")
+)
+
+// RevertError is returned by EstimateGas when the transaction reverts
+// whatever its gas limit. Data is the output of REVERT and Reason the
+// message it encodes, if it is an Error(string).
+type RevertError struct {
+	Data   []byte
+	Reason string
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return ErrExecutionReverted.Error()
+	}
+	return ErrExecutionReverted.Error() + ": " + e.Reason
+}
+
+func (e *RevertError) Unwrap() error {
+	return ErrExecutionReverted
+}
+
+// ErrStackUnderflow is returned when an instruction needs more items than
+// the stack holds.
+type ErrStackUnderflow struct {
+	StackLen int
+	Required int
+}
+
+func (e *ErrStackUnderflow) Error() string {
+	return fmt.Sprintf("stack underflow (%d <=> %d)", e.StackLen, e.Required)
+}
+
+// ErrStackOverflow is returned when an instruction would grow the stack
+// past its limit.
+type ErrStackOverflow struct {
+	StackLen int
+	Limit    int
+}
+
+func (e *ErrStackOverflow) Error() string {
+	return fmt.Sprintf("stack limit reached %d (%d)", e.StackLen, e.Limit)
+}
+
+// ErrInvalidOpCode is returned for undefined opcodes and for INVALID.
+type ErrInvalidOpCode struct {
+	OpCode OpCode
+}
+
+func (e *ErrInvalidOpCode) Error() string {
+	return fmt.Sprintf("invalid opcode: %v", e.OpCode)
+}
+
+// ExecutionError records the instruction that stopped execution.
+// Use errors.Is and errors.As to inspect the underlying cause.
+type ExecutionError struct {
+	PC  uint64
+	Op  OpCode
+	Err error
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("pc %d, op %v: %v", e.PC, e.Op, e.Err)
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,96 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testGas is the gas given to test executions that are not about gas.
+const testGas = 10000000
+
+func runHex(t *testing.T, code string) *ExecutionResult {
+	t.Helper()
+	bin, err := hex.DecodeString(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(bin, testGas)
+}
+
+func TestExecutionErrors(t *testing.T) {
+	var underflow *ErrStackUnderflow
+	var invalidOp *ErrInvalidOpCode
+
+	tests := []struct {
+		name   string
+		code   string
+		pc     uint64
+		op     OpCode
+		target interface{}
+		is     error
+	}{
+		{name: "ADD on empty stack", code: "01", pc: 0, op: 0x01, target: &underflow},
+		{name: "DUP1 on empty stack", code: "80", pc: 0, op: 0x80, target: &underflow},
+		{name: "SWAP1 with one item", code: "600190", pc: 2, op: 0x90, target: &underflow},
+		{name: "INVALID", code: "6001fe", pc: 2, op: 0xfe, target: &invalidOp},
+		{name: "undefined opcode", code: "0c", pc: 0, op: 0x0c, target: &invalidOp},
+		{name: "JUMP into push data", code: "6004566000605b", pc: 2, op: 0x56, is: ErrInvalidJump},
+		{name: "RETURNDATACOPY past the end", code: "6001600060003e", pc: 6, op: 0x3e, is: ErrReturnDataOutOfBounds},
+		{name: "REVERT", code: "60006000fd", pc: 4, op: 0xfd, is: ErrExecutionReverted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHex(t, tt.code)
+			if res.Success {
+				t.Fatal("expected failure")
+			}
+			var execErr *ExecutionError
+			if !errors.As(res.Err, &execErr) {
+				t.Fatalf("expected *ExecutionError, got %T: %v", res.Err, res.Err)
+			}
+			if execErr.PC != tt.pc || execErr.Op != tt.op {
+				t.Errorf("failed at pc %d op 0x%02x, want pc %d op 0x%02x", execErr.PC, execErr.Op, tt.pc, tt.op)
+			}
+			if tt.target != nil && !errors.As(res.Err, tt.target) {
+				t.Errorf("error %v does not match %T", res.Err, tt.target)
+			}
+			if tt.is != nil && !errors.Is(res.Err, tt.is) {
+				t.Errorf("error %v is not %v", res.Err, tt.is)
+			}
+		})
+	}
+}
+
+func TestRevertKeepsOutput(t *testing.T) {
+	// MSTORE8 0xf1 at 0, REVERT with the first two bytes
+	res := runHex(t, "60f160005360026000fd")
+	if res.Success || !errors.Is(res.Err, ErrExecutionReverted) {
+		t.Fatalf("expected a revert, got %v", res.Err)
+	}
+	if len(res.ReturnData) != 2 || res.ReturnData[0] != 0xf1 || res.ReturnData[1] != 0 {
+		t.Errorf("return data = %x, want f100", res.ReturnData)
+	}
+}
+
+func TestStackLimit(t *testing.T) {
+	// 1024 pushes fill the stack exactly
+	code := strings.Repeat("6001", stackLimit)
+	if res := runHex(t, code); !res.Success || len(res.Stack) != stackLimit {
+		t.Fatalf("filling the stack failed: %v", res.Err)
+	}
+
+	var overflow *ErrStackOverflow
+	for _, extra := range []string{"6001", "80", "58"} {
+		res := runHex(t, code+extra)
+		if res.Success || !errors.As(res.Err, &overflow) {
+			t.Errorf("%s on a full stack: expected stack overflow, got %v", extra, res.Err)
+		}
+	}
+
+	// SWAP and binary ops don't grow the stack, so they still run when it is full
+	if res := runHex(t, code+"9001"); !res.Success {
+		t.Errorf("SWAP1 ADD on a full stack failed: %v", res.Err)
+	}
+}
@@ -0,0 +1,103 @@
+package evm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+// EstimateGas returns the lowest gas limit at which msg executes
+// successfully, searching up to the gas limit of msg, or of the block if it
+// has none, capped at gasCap if that is not zero and at what the sender can
+// pay for. Every attempt is reverted to a snapshot, so the state, including
+// its access list, is left as it was.
+//
+// If msg fails at the highest limit, the error wraps the reason, and is a
+// *RevertError if msg reverted.
+func (evm *EVM) EstimateGas(msg *Message, gasCap uint64) (uint64, error) {
+	defer func(txCtx TxContext) { evm.TxContext = txCtx }(evm.TxContext)
+
+	hi := msg.GasLimit
+	if hi == 0 {
+		hi = evm.Context.GasLimit
+	}
+	if gasCap != 0 && hi > gasCap {
+		hi = gasCap
+	}
+	if msg.GasPrice != nil && !msg.GasPrice.IsZero() {
+		balance := evm.StateDB.GetBalance(msg.From)
+		if msg.Value != nil {
+			if balance.Lt(msg.Value) {
+				return 0, fmt.Errorf("%w: address %v", ErrInsufficientFunds, msg.From)
+			}
+			balance = new(uint256.Int).Sub(balance, msg.Value)
+		}
+		allowance := new(uint256.Int).Div(balance, msg.GasPrice)
+		if allowance.IsUint64() && hi > allowance.Uint64() {
+			hi = allowance.Uint64()
+		}
+	}
+
+	res, err := evm.tryGasLimit(*msg, hi)
+	if err != nil {
+		return 0, err
+	}
+	if !res.Success {
+		if errors.Is(res.Err, ErrExecutionReverted) {
+			reason, _ := unpackRevert(res.ReturnData)
+			return 0, &RevertError{Data: res.ReturnData, Reason: reason}
+		}
+		return 0, fmt.Errorf("gas required exceeds allowance (%d): %w", hi, res.Err)
+	}
+
+	// the transaction needs at least the gas it was charged
+	lo := res.GasUsed - 1
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		res, err := evm.tryGasLimit(*msg, mid)
+		switch {
+		case errors.Is(err, ErrIntrinsicGas), errors.Is(err, ErrFloorDataGas):
+			lo = mid
+		case err != nil:
+			return 0, err
+		case !res.Success:
+			lo = mid
+		default:
+			hi = mid
+		}
+	}
+	return hi, nil
+}
+
+// tryGasLimit executes msg with the given gas limit and reverts its changes.
+func (evm *EVM) tryGasLimit(msg Message, gas uint64) (*TransactionResult, error) {
+	msg.GasLimit = gas
+	snapshot := evm.StateDB.Snapshot()
+	defer evm.StateDB.RevertToSnapshot(snapshot)
+	return evm.applyMessage(&msg)
+}
+
+// revertSelector is the selector of Error(string), the revert data of
+// Solidity's require and revert.
+var revertSelector = Keccak256([]byte("Error(string)"))[:4]
+
+// unpackRevert returns the message of ABI-encoded Error(string) revert
+// data, and whether data is one.
+func unpackRevert(data []byte) (string, bool) {
+	if len(data) < 4+32+32 || !bytes.Equal(data[:4], revertSelector) {
+		return "", false
+	}
+	data = data[4:]
+	offset := new(uint256.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data))-32 {
+		return "", false
+	}
+	data = data[offset.Uint64():]
+	size := new(uint256.Int).SetBytes(data[:32])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data))-32 {
+		return "", false
+	}
+	return string(data[32 : 32+size.Uint64()]), true
+}
@@ -0,0 +1,144 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestEstimateGas(t *testing.T) {
+	var (
+		sender   = HexToAddress("0xaa")
+		store    = HexToAddress("0xbb")
+		proxy    = HexToAddress("0xcc")
+		transfer = HexToAddress("0xdd")
+	)
+	for _, tt := range []struct {
+		name string
+		to   Address
+		want uint64
+	}{
+		{name: "transfer", to: transfer, want: 21000},
+		// PUSH1 1, PUSH1 0, SSTORE into a cold, empty slot
+		{name: "sstore", to: store, want: 21000 + 3 + 3 + 2100 + 20000},
+		// the proxy forwards all but a 64th of its gas to the store
+		{name: "nested call", to: proxy},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryStateDB()
+			db.AddBalance(sender, uint256.NewInt(1000000))
+			db.SetCode(store, fromHex("6001600055"))
+			// CALL 0xbb with all the gas, and revert if the call failed
+			db.SetCode(proxy, fromHex("6000600060006000600060bb5af1"+"15601357"+"00"+"5b60006000fd"))
+			evm := NewEVM(BlockContext{GasLimit: 1000000}, TxContext{}, db, Config{})
+
+			msg := &Message{From: sender, To: &tt.to, Value: uint256.NewInt(1)}
+			got, err := evm.EstimateGas(msg, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != 0 && got != tt.want {
+				t.Errorf("estimate %d, want %d", got, tt.want)
+			}
+			if db.GetState(store, Hash{}) != (Hash{}) || db.GetNonce(sender) != 0 || db.GetBalance(sender).Uint64() != 1000000 {
+				t.Fatal("estimation changed the state")
+			}
+
+			// the estimate is the lowest limit at which the store happens
+			for _, gas := range []uint64{got - 1, got} {
+				snapshot := db.Snapshot()
+				res, err := evm.applyMessage(&Message{From: sender, To: &tt.to, Value: uint256.NewInt(1), GasLimit: gas})
+				stored := db.GetState(store, Hash{}) != (Hash{})
+				db.RevertToSnapshot(snapshot)
+				if tt.to != transfer && (err != nil || res.Success != (gas == got) || stored != (gas == got)) {
+					t.Errorf("gas %d: success %v, stored %v (%v)", gas, res != nil && res.Success, stored, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEstimateGasRevert(t *testing.T) {
+	// Error("nope"), copied from the end of the code and reverted with
+	reason, _ := hex.DecodeString("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000")
+	code := append(fromHex("6064600c600039"+"60646000fd"), reason...)
+
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	db.SetCode(to, code)
+	evm := NewEVM(BlockContext{GasLimit: 1000000}, TxContext{}, db, Config{})
+	_, err := evm.EstimateGas(&Message{From: HexToAddress("0xaa"), To: &to}, 0)
+
+	var revertErr *RevertError
+	if !errors.As(err, &revertErr) || !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("got %v, want a RevertError", err)
+	}
+	if revertErr.Reason != "nope" || err.Error() != "execution reverted: nope" {
+		t.Errorf("reason %q, error %q", revertErr.Reason, err)
+	}
+}
+
+func TestEstimateGasCap(t *testing.T) {
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	// JUMPDEST, PUSH1 0, JUMP: never succeeds
+	db.SetCode(to, fromHex("5b600056"))
+	evm := NewEVM(BlockContext{GasLimit: 1000000}, TxContext{}, db, Config{})
+	_, err := evm.EstimateGas(&Message{From: HexToAddress("0xaa"), To: &to}, 50000)
+	var revertErr *RevertError
+	if !errors.Is(err, ErrOutOfGas) || errors.As(err, &revertErr) {
+		t.Errorf("got %v, want out of gas", err)
+	}
+
+	// a sender that can pay for less than the intrinsic gas
+	db.AddBalance(HexToAddress("0xaa"), uint256.NewInt(20000))
+	transfer := HexToAddress("0xdd")
+	_, err = evm.EstimateGas(&Message{From: HexToAddress("0xaa"), To: &transfer, GasPrice: uint256.NewInt(1)}, 0)
+	if !errors.Is(err, ErrIntrinsicGas) {
+		t.Errorf("got %v, want ErrIntrinsicGas", err)
+	}
+}
+
+func TestUnpackRevert(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"08c379a0",
+		// wrong selector
+		"deadbeef" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		// length past the end of the data
+		"08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000021",
+	} {
+		if reason, ok := unpackRevert(fromHex(data)); ok {
+			t.Errorf("%q unpacked to %q", data, reason)
+		}
+	}
+}
+
+func TestEstimateGasKeepsAccessList(t *testing.T) {
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	// PUSH1 0, SLOAD, touching a slot the caller has not accessed
+	db.SetCode(to, fromHex("600054"))
+	warm := HexToAddress("0xee")
+	db.AddSlotToAccessList(warm, HexToHash("0x01"))
+
+	evm := NewEVM(BlockContext{GasLimit: 1000000}, TxContext{}, db, Config{})
+	if _, err := evm.EstimateGas(&Message{From: HexToAddress("0xaa"), To: &to}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, slotOk := db.SlotInAccessList(warm, HexToHash("0x01")); !slotOk {
+		t.Error("access list of the caller was replaced")
+	}
+	if db.AddressInAccessList(to) || db.AddressInAccessList(HexToAddress("0xaa")) {
+		t.Error("addresses warmed by the estimation are still warm")
+	}
+}
@@ -6,389 +6,452 @@
  *
  * - Install Golang: https://golang.org/doc/install
  * - Go to the `go` directory: `cd go`
- * - Edit `evm.go` (this file!), see TODO below
  * - Run `go test -v` to run the tests
  * - Run `go test -run TestName` to run one test
+ *
+ * Gas is metered per frame: the interpreter charges each instruction its
+ * constant gas from the jump table, then its dynamic gas (memory
+ * expansion, operand sizes, cold accesses, forwarded call gas) before
+ * executing it. The evm.json tests run with Config.NoGasMetering, which
+ * turns all of this off.
  */
 
-package main
+package evm
 
 //go:generate go run testgen.go
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/holiman/uint256"
 )
 
+// Fork selects the set of protocol rules the interpreter follows.
+type Fork int
+
 const (
-	opStop = 0x00
-
-	opAdd    = 0x01
-	opMul    = 0x02
-	opSub    = 0x03
-	opDiv    = 0x04
-	opSDiv   = 0x05
-	opMod    = 0x06
-	opSMod   = 0x07
-	opAddMod = 0x08
-	opMulMod = 0x09
-	opExp    = 0x0a
-
-	opSignExtend = 0x0b
-	opSha3       = 0x20
-
-	opLT     = 0x10
-	opGT     = 0x11
-	opSLT    = 0x12
-	opSGT    = 0x13
-	opEQ     = 0x14
-	opIsZero = 0x15
-
-	opAnd = 0x16
-	opOr  = 0x17
-	opXor = 0x18
-	opNot = 0x19
-
-	opByte = 0x1a
-	opShl  = 0x1b
-	opShr  = 0x1c
-	opSar  = 0x1d
-
-	opPop      = 0x50
-	opMLoad    = 0x51
-	opMStore   = 0x52
-	opMStore8  = 0x53
-	opJump     = 0x56
-	opJumpI    = 0x57
-	opPC       = 0x58
-	opMSize    = 0x59
-	opGas      = 0x5a
-	opJumpDest = 0x5b
-	opPush1    = 0x60
-	opPush32   = 0x7f
-	opDup1     = 0x80
-	opDup16    = 0x8f
-	opSwap1    = 0x90
-	opSwap16   = 0x9f
-
-	opInvalid = 0xfe
+	Berlin Fork = iota + 1
+	London
+	Paris
+	Shanghai
+	Cancun
+	Prague
+
+	// LatestFork is used when Config.Fork is left unset.
+	LatestFork = Prague
 )
 
-func evm(code []byte) (success bool, stack []uint256.Int) {
-	defer func() {
-		if err := recover(); err != nil {
-			fmt.Printf("ERR: %v\n", err)
-			success = false
-		}
-	}()
-
-	pc := uint64(0)
-	mem := NewMemory()
-
-	for pc < uint64(len(code)) {
-		op := code[pc]
-		pc++
-
-		if op >= opPush1 && op <= opPush32 {
-			pushLen := uint64(op-opPush1) + 1
-			if pushLen > 32 || uint64(len(code)) < pc+pushLen {
-				return false, stack
-			}
-			bytes := code[pc:(pc + pushLen)]
-			stack = push(stack, uint256.NewInt(0).SetBytes(bytes))
-			pc += pushLen
-			continue
-		}
+var forkNames = map[Fork]string{
+	Berlin:   "Berlin",
+	London:   "London",
+	Paris:    "Paris",
+	Shanghai: "Shanghai",
+	Cancun:   "Cancun",
+	Prague:   "Prague",
+}
 
-		if op >= opDup1 && op <= opDup16 {
-			pos := uint64(op - opDup1)
-			if uint64(len(stack)) < pos {
-				return false, stack
-			}
-			stack = push(stack, &stack[pos])
-			continue
-		}
+func (f Fork) String() string {
+	if name, ok := forkNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Fork(%d)", int(f))
+}
 
-		if op >= opSwap1 && op <= opSwap16 {
-			pos := uint64(op-opSwap1) + 1
-			if uint64(len(stack)) < pos {
-				return false, stack
-			}
-			stack[0], stack[pos] = stack[pos], stack[0]
-			continue
-		}
+// DefaultMaxMemory is the memory cap of a frame when Config.MaxMemory is
+// unset. It is well above what the gas limit of any block can pay for.
+const DefaultMaxMemory = 32 << 20
+
+// Config holds the chain-level settings of an EVM.
+type Config struct {
+	Fork    Fork
+	ChainID uint64
+
+	// MaxMemory caps the memory of a single frame in bytes. Instructions
+	// that would expand memory past it fail with ErrMemoryLimit.
+	MaxMemory uint64
+
+	// EagerSelfDestruct makes SELFDESTRUCT destroy the account whatever the
+	// fork and clear its code and nonce at once, instead of at the end of
+	// the transaction. This is the simplification the evm.json test cases
+	// expect; it is not how any fork behaves.
+	EagerSelfDestruct bool
+
+	// NoGasMetering turns gas off: instructions cost nothing, calls and
+	// creations ignore the gas they are given and GAS pushes 2^256-1. The
+	// evm.json test cases are written for this mode.
+	NoGasMetering bool
+}
 
-		switch op {
-		case opStop:
-			return true, stack
-		case opPop:
-			stack, _ = pop(stack, 1)
-		case opAdd:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Add(&x, &y))
-		case opMul:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Mul(&x, &y))
-		case opSub:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Sub(&x, &y))
-		case opDiv:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Div(&x, &y))
-		case opMod:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Mod(&x, &y))
-		case opAddMod:
-			var x, y, z uint256.Int
-			stack, x, y, z = pop3(stack)
-			stack = push(stack, uint256.NewInt(0).AddMod(&x, &y, &z))
-		case opMulMod:
-			var x, y, z uint256.Int
-			stack, x, y, z = pop3(stack)
-			stack = push(stack, uint256.NewInt(0).MulMod(&x, &y, &z))
-		case opExp:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Exp(&x, &y))
-		case opSignExtend:
-			var b, x uint256.Int
-			stack, b, x = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).ExtendSign(&x, &b))
-		case opSDiv:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).SDiv(&x, &y))
-		case opSMod:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).SMod(&x, &y))
-		case opLT:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = pushBool(stack, x.Lt(&y))
-		case opGT:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = pushBool(stack, x.Gt(&y))
-		case opSLT:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = pushBool(stack, x.Slt(&y))
-		case opSGT:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = pushBool(stack, x.Sgt(&y))
-		case opEQ:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = pushBool(stack, x.Eq(&y))
-		case opIsZero:
-			var x uint256.Int
-			stack, x = pop1(stack)
-			stack = pushBool(stack, x.IsZero())
-		case opAnd:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).And(&x, &y))
-		case opOr:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Or(&x, &y))
-		case opXor:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Xor(&x, &y))
-		case opNot:
-			var x uint256.Int
-			stack, x = pop1(stack)
-			stack = push(stack, uint256.NewInt(0).Not(&x))
-		case opByte:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, y.Byte(&x))
-		case opShl:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Lsh(&y, uint(x.Uint64())))
-		case opShr:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).Rsh(&y, uint(x.Uint64())))
-		case opSar:
-			var x, y uint256.Int
-			stack, x, y = pop2(stack)
-			stack = push(stack, uint256.NewInt(0).SRsh(&y, uint(x.Uint64())))
-		case opInvalid:
-			return false, stack
-		case opPC:
-			stack = push(stack, uint256.NewInt(pc-1))
-		case opGas:
-			// TODO: gas is not supported by this version of the course
-			stack = push(stack, uint256.NewInt(0).Not(uint256.NewInt(0)))
-		case opJump:
-			var dest uint256.Int
-			stack, dest = pop1(stack)
-			dest64, overflow := dest.Uint64WithOverflow()
-			if overflow || !validJumpDest(code, dest64) {
-				// overflow = dest is more than MaxUint64, and Go can't handle that
-				return false, stack
-			}
-			pc = dest64
-		case opJumpDest: // noop
-		case opJumpI:
-			var dest, doJump uint256.Int
-			stack, dest, doJump = pop2(stack)
-			dest64, overflow := dest.Uint64WithOverflow()
-			if overflow || !validJumpDest(code, dest64) {
-				// overflow = dest is more than MaxUint64, and Go can't handle that
-				return false, stack
-			}
-			if !doJump.IsZero() {
-				pc = dest64
-			}
-		case opMLoad:
-			var offset uint256.Int
-			stack, offset = pop1(stack)
-			stack = push(stack, mem.Get(offset.Uint64()))
-		case opMStore:
-			var offset, val uint256.Int
-			stack, offset, val = pop2(stack)
-			mem.Put(offset.Uint64(), &val)
-		case opMStore8:
-			var offset, val uint256.Int
-			stack, offset, val = pop2(stack)
-			mem.PutByte(offset.Uint64(), byte(val.Uint64()))
-		case opMSize:
-			stack = push(stack, uint256.NewInt(mem.Len()))
-		case opSha3:
-			var offset, size uint256.Int
-			stack, offset, size = pop2(stack)
-			stack = push(stack, mem.Sha3(offset.Uint64(), size.Uint64()))
-		}
-	}
+type (
+	// CanTransferFunc reports whether the account has enough balance to
+	// transfer the amount.
+	CanTransferFunc func(StateDB, Address, *uint256.Int) bool
+	// TransferFunc moves the amount from the sender to the recipient.
+	TransferFunc func(db StateDB, sender, recipient Address, amount *uint256.Int)
+	// GetHashFunc returns the hash of the block with the given number.
+	GetHashFunc func(uint64) Hash
+)
+
+// CanTransfer is the default CanTransferFunc. It checks the balance of the
+// account.
+func CanTransfer(db StateDB, addr Address, amount *uint256.Int) bool {
+	return db.GetBalance(addr).Cmp(amount) >= 0
+}
+
+// Transfer is the default TransferFunc.
+func Transfer(db StateDB, sender, recipient Address, amount *uint256.Int) {
+	db.SubBalance(sender, amount)
+	db.AddBalance(recipient, amount)
+}
 
-	return true, stack
+// BlockContext holds the values of the block the code executes in.
+type BlockContext struct {
+	// CanTransfer and Transfer move value between accounts. If they are
+	// nil, NewEVM uses the functions of the same name.
+	CanTransfer CanTransferFunc
+	Transfer    TransferFunc
+	// GetHash backs BLOCKHASH. It is only called for the 256 most recent
+	// blocks; if it is nil, BLOCKHASH returns zero.
+	GetHash GetHashFunc
+
+	Coinbase    Address
+	GasLimit    uint64
+	Number      uint64
+	Time        uint64
+	Difficulty  *uint256.Int // PREVRANDAO after the merge
+	BaseFee     *uint256.Int // London and later
+	BlobBaseFee *uint256.Int // Cancun and later
 }
 
-func validJumpDest(code []byte, dest uint64) bool {
-	if uint64(len(code)) < dest {
-		return false // destination is past the end of the code
+// TxContext holds the values of the transaction the code executes in.
+// They are the same in every frame of the transaction.
+type TxContext struct {
+	Origin     Address
+	GasPrice   *uint256.Int
+	BlobHashes []Hash // Cancun and later
+}
+
+// EVM executes bytecode against a block, a transaction and the world state.
+// An EVM is not safe for concurrent use.
+type EVM struct {
+	Context BlockContext
+	TxContext
+	StateDB StateDB
+	Config  Config
+
+	table    *JumpTable
+	depth    int  // of the frame being executed, 1 for the outermost one
+	readOnly bool // inside a STATICCALL
+
+	// returnData is the output of the last call made by the executing
+	// frame, read by RETURNDATASIZE and RETURNDATACOPY.
+	returnData []byte
+	// callGasTemp is the gas the call being executed forwards, worked out
+	// by its gas function.
+	callGasTemp uint64
+}
+
+// ExecutionResult is the outcome of running code. Stack is listed top
+// first, which is the order the test cases in evm.json use. ReturnData is
+// the output of RETURN or REVERT. Logs holds the logs emitted during a
+// successful execution and Bloom summarises them. GasUsed does not account
+// for Refund, the gas the refund counter gives back, which is capped at a
+// fraction of GasUsed (EIP-3529).
+type ExecutionResult struct {
+	Success    bool
+	Stack      []uint256.Int
+	ReturnData []byte
+	Logs       []*Log
+	Bloom      Bloom
+	GasUsed    uint64
+	Refund     uint64
+	Err        error
+}
+
+// NewEVM returns an EVM for the given block, transaction and state. A nil
+// statedb is replaced by an empty in-memory state.
+func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, config Config) *EVM {
+	if statedb == nil {
+		statedb = NewMemoryStateDB()
+	}
+	if blockCtx.CanTransfer == nil {
+		blockCtx.CanTransfer = CanTransfer
+	}
+	if blockCtx.Transfer == nil {
+		blockCtx.Transfer = Transfer
+	}
+	if config.Fork == 0 {
+		config.Fork = LatestFork
 	}
-	if code[dest] != opJumpDest {
-		return false
+	if config.MaxMemory == 0 {
+		config.MaxMemory = DefaultMaxMemory
 	}
+	return &EVM{
+		Context:   blockCtx,
+		TxContext: txCtx,
+		StateDB:   statedb,
+		Config:    config,
+		table:     instructionSetForFork(config.Fork),
+	}
+}
 
-	// loop through code and make sure jump dest is not inside PUSH data
-	for pc := uint64(0); pc < uint64(len(code)); {
-		op := code[pc]
-		pc++
+// Run executes code in a fresh frame with no caller, input or value and
+// reports the outcome.
+func (evm *EVM) Run(code []byte, gas uint64) *ExecutionResult {
+	return evm.RunContract(NewContract(Address{}, Address{}, nil, code, nil, gas))
+}
 
-		if op < opPush1 || op > opPush32 {
-			continue
+// RunContract executes the code of contract in a fresh frame and reports
+// the outcome. If execution fails, the state changes it made are reverted.
+func (evm *EVM) RunContract(contract *Contract) *ExecutionResult {
+	scope := &ScopeContext{
+		Memory:   NewMemory(),
+		Stack:    newstack(),
+		Contract: contract,
+	}
+	defer returnStack(scope.Stack)
+
+	var (
+		snapshot = evm.StateDB.Snapshot()
+		numLogs  = len(evm.StateDB.Logs())
+		gas      = contract.Gas
+		// refunds earned by earlier runs on the state are not this run's
+		refund = evm.StateDB.GetRefund()
+	)
+	ret, err := evm.run(scope)
+	if err != nil {
+		evm.StateDB.RevertToSnapshot(snapshot)
+		if !errors.Is(err, ErrExecutionReverted) {
+			contract.Gas = 0
 		}
+	}
+	logs := evm.StateDB.Logs()[numLogs:]
+	result := &ExecutionResult{
+		Success:    err == nil,
+		Stack:      scope.Stack.TopFirst(),
+		ReturnData: ret,
+		Logs:       logs,
+		Bloom:      CreateBloom(logs),
+		Err:        err,
+	}
+	if !evm.Config.NoGasMetering {
+		result.GasUsed = gas - contract.Gas
+		result.Refund = evm.refund(result.GasUsed, refund)
+	}
+	return result
+}
 
-		numBitsToPush := uint64(op) - opPush1 + 1
-		if pc <= dest && dest < pc+numBitsToPush {
-			return false
+// refund returns what the refund counter gained since it held since,
+// capped at the part of gasUsed the fork allows to be refunded. A counter
+// that went below since gives no refund.
+func (evm *EVM) refund(gasUsed, since uint64) uint64 {
+	quotient := refundQuotient
+	if evm.Config.Fork >= London {
+		quotient = refundQuotientEIP3529
+	}
+	counter := evm.StateDB.GetRefund()
+	if counter < since {
+		return 0
+	}
+	return min64(counter-since, gasUsed/quotient)
+}
+
+// callCreateDepth is the maximum depth of nested calls and creations.
+const callCreateDepth = 1024
+
+// Call runs the code of addr with the given input as a message from
+// caller, transferring value to addr first. If the call fails, its state
+// changes are reverted; a revert also returns its output. It returns the
+// gas the callee did not use, which is none if it failed without reverting.
+// A nil value is treated as zero, as in Contract.
+func (evm *EVM) Call(caller, addr Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	if evm.depth > callCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if !value.IsZero() && !evm.Context.CanTransfer(evm.StateDB, caller, value) {
+		return nil, gas, ErrInsufficientBalance
+	}
+	snapshot := evm.StateDB.Snapshot()
+	if !evm.StateDB.Exist(addr) {
+		// calling a non-existent account without value is a no-op and
+		// does not create it (EIP-158)
+		if value.IsZero() {
+			return nil, gas, nil
 		}
-		pc += numBitsToPush
+		evm.StateDB.CreateAccount(addr)
 	}
+	evm.Context.Transfer(evm.StateDB, caller, addr, value)
 
-	return true
+	if code := evm.StateDB.GetCode(addr); len(code) > 0 {
+		contract := NewContract(caller, addr, value, code, input, gas)
+		contract.CodeHash = evm.StateDB.GetCodeHash(addr)
+		ret, err = evm.runFrame(contract, false)
+		gas = contract.Gas
+	}
+	return ret, evm.endFrame(snapshot, gas, err), err
 }
 
-func push(stack []uint256.Int, i *uint256.Int) []uint256.Int {
-	return append([]uint256.Int{*i}, stack...)
+// CallCode runs the code of addr in the context of caller: storage,
+// balance and ADDRESS are those of caller, which is also the message
+// sender. The value is not transferred, but the balance of caller must
+// cover it.
+func (evm *EVM) CallCode(caller, addr Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	if evm.depth > callCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if !evm.Context.CanTransfer(evm.StateDB, caller, value) {
+		return nil, gas, ErrInsufficientBalance
+	}
+	snapshot := evm.StateDB.Snapshot()
+	if code := evm.StateDB.GetCode(addr); len(code) > 0 {
+		contract := NewContract(caller, caller, value, code, input, gas)
+		contract.CodeHash = evm.StateDB.GetCodeHash(addr)
+		ret, err = evm.runFrame(contract, false)
+		gas = contract.Gas
+	}
+	return ret, evm.endFrame(snapshot, gas, err), err
 }
 
-func pushBool(stack []uint256.Int, b bool) []uint256.Int {
-	if b {
-		stack = push(stack, uint256.NewInt(1))
-	} else {
-		stack = push(stack, uint256.NewInt(0))
+// DelegateCall runs the code of addr in the context of caller, keeping the
+// sender and value of the message that is executing caller, given as
+// originCaller and value.
+func (evm *EVM) DelegateCall(originCaller, caller, addr Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
+	if evm.depth > callCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	snapshot := evm.StateDB.Snapshot()
+	if code := evm.StateDB.GetCode(addr); len(code) > 0 {
+		contract := NewContract(originCaller, caller, value, code, input, gas)
+		contract.CodeHash = evm.StateDB.GetCodeHash(addr)
+		ret, err = evm.runFrame(contract, false)
+		gas = contract.Gas
 	}
-	return stack
+	return ret, evm.endFrame(snapshot, gas, err), err
 }
 
-func pop(stack []uint256.Int, n int) ([]uint256.Int, []uint256.Int) {
-	if n > len(stack) {
-		panic(fmt.Errorf("stack len (%d) is smaller than %d", len(stack), n))
+// StaticCall is Call without a value, in which the callee and every frame
+// below it are read-only: instructions that modify state fail with
+// ErrWriteProtection.
+func (evm *EVM) StaticCall(caller, addr Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if evm.depth > callCreateDepth {
+		return nil, gas, ErrDepth
 	}
-	vals := make([]uint256.Int, n)
-	copy(vals, stack[:n])
-	return stack[n:], vals
+	snapshot := evm.StateDB.Snapshot()
+	if code := evm.StateDB.GetCode(addr); len(code) > 0 {
+		contract := NewContract(caller, addr, new(uint256.Int), code, input, gas)
+		contract.CodeHash = evm.StateDB.GetCodeHash(addr)
+		ret, err = evm.runFrame(contract, true)
+		gas = contract.Gas
+	}
+	return ret, evm.endFrame(snapshot, gas, err), err
 }
 
-func pop1(stack []uint256.Int) ([]uint256.Int, uint256.Int) {
-	stack, vals := pop(stack, 1)
-	return stack, vals[0]
+const (
+	// MaxCodeSize is the size limit of deployed code (EIP-170).
+	MaxCodeSize = 24576
+	// MaxInitCodeSize is the size limit of init code from Shanghai on
+	// (EIP-3860).
+	MaxInitCodeSize = 2 * MaxCodeSize
+)
+
+// Create deploys a contract at the address derived from caller and its
+// nonce, running code as init code. It returns the output of the init code,
+// which is the deployed code on success. A nil value is treated as zero.
+func (evm *EVM) Create(caller Address, code []byte, gas uint64, value *uint256.Int) (ret []byte, contractAddr Address, leftOverGas uint64, err error) {
+	contractAddr = CreateAddress(caller, evm.StateDB.GetNonce(caller))
+	return evm.create(caller, code, gas, value, contractAddr)
 }
 
-func pop2(stack []uint256.Int) ([]uint256.Int, uint256.Int, uint256.Int) {
-	stack, vals := pop(stack, 2)
-	return stack, vals[0], vals[1]
+// Create2 is Create with the address derived from caller, salt and the
+// hash of code (EIP-1014).
+func (evm *EVM) Create2(caller Address, code []byte, gas uint64, value *uint256.Int, salt *uint256.Int) (ret []byte, contractAddr Address, leftOverGas uint64, err error) {
+	contractAddr = CreateAddress2(caller, salt.Bytes32(), Keccak256(code))
+	return evm.create(caller, code, gas, value, contractAddr)
 }
 
-func pop3(stack []uint256.Int) ([]uint256.Int, uint256.Int, uint256.Int, uint256.Int) {
-	stack, vals := pop(stack, 3)
-	return stack, vals[0], vals[1], vals[2]
+func (evm *EVM) create(caller Address, code []byte, gas uint64, value *uint256.Int, address Address) (ret []byte, createAddress Address, leftOverGas uint64, err error) {
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	if evm.depth > callCreateDepth {
+		return nil, Address{}, gas, ErrDepth
+	}
+	if !evm.Context.CanTransfer(evm.StateDB, caller, value) {
+		return nil, Address{}, gas, ErrInsufficientBalance
+	}
+	// the nonce is spent even if the creation fails
+	nonce := evm.StateDB.GetNonce(caller)
+	if nonce+1 < nonce {
+		return nil, Address{}, gas, ErrNonceUintOverflow
+	}
+	evm.StateDB.SetNonce(caller, nonce+1)
+	// the new contract is warm even if the creation fails (EIP-2929)
+	evm.StateDB.AddAddressToAccessList(address)
+
+	// an address with a nonce or code is already in use (EIP-684)
+	contractHash := evm.StateDB.GetCodeHash(address)
+	if evm.StateDB.GetNonce(address) != 0 || (contractHash != (Hash{}) && contractHash != emptyCodeHash) {
+		return nil, Address{}, 0, ErrContractAddressCollision
+	}
+	snapshot := evm.StateDB.Snapshot()
+	evm.StateDB.CreateAccount(address)
+	evm.StateDB.CreateContract(address)
+	// new contracts start with nonce 1 (EIP-161)
+	evm.StateDB.SetNonce(address, 1)
+	evm.Context.Transfer(evm.StateDB, caller, address, value)
+
+	contract := NewContract(caller, address, value, code, nil, gas)
+	ret, err = evm.runFrame(contract, false)
+	if err == nil && len(ret) > MaxCodeSize {
+		err = ErrMaxCodeSizeExceeded
+	}
+	// code starting with 0xEF is reserved for EOF (EIP-3541)
+	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.Config.Fork >= London {
+		err = ErrInvalidCode
+	}
+	if err == nil {
+		createDataGas := uint64(len(ret)) * createDataGas
+		if evm.Config.NoGasMetering || contract.UseGas(createDataGas) {
+			evm.StateDB.SetCode(address, ret)
+		} else {
+			err = ErrCodeStoreOutOfGas
+		}
+	}
+	return ret, address, evm.endFrame(snapshot, contract.Gas, err), err
+}
+
+// endFrame reverts the state changes of a failed frame and returns the gas
+// it leaves to its caller: all of gas, unless it failed without reverting.
+func (evm *EVM) endFrame(snapshot int, gas uint64, err error) uint64 {
+	if err == nil {
+		return gas
+	}
+	evm.StateDB.RevertToSnapshot(snapshot)
+	if errors.Is(err, ErrExecutionReverted) {
+		return gas
+	}
+	return 0
 }
 
-//func main() {
-//	content, err := ioutil.ReadFile("../evm.json")
-//	if err != nil {
-//		log.Fatal("Error when opening file: ", err)
-//	}
-//
-//	var payload []testCase
-//	err = json.Unmarshal(content, &payload)
-//	if err != nil {
-//		log.Fatal("Error during json.Unmarshal(): ", err)
-//	}
-//
-//	for index, test := range payload {
-//		fmt.Printf("Test #%v of %v: %v\n", index+1, len(payload), test.Name)
-//
-//		bin, err := hex.DecodeString(test.Code.Bin)
-//		if err != nil {
-//			log.Fatal("Error during hex.DecodeString(): ", err)
-//		}
-//
-//		var expectedStack []uint256.Int
-//		for _, s := range test.Expect.Stack {
-//			i, err := uint256.FromHex(s)
-//			if err != nil {
-//				log.Fatal("Error during big.Int.SetString(): ", err)
-//			}
-//			expectedStack = append(expectedStack, *i)
-//		}
-//
-//		// Note: as the test cases get more complex, you'll need to modify this
-//		// to pass down more arguments to the evm function and return more than
-//		// just the stack.
-//		success, stack := evm(bin)
-//
-//		match := len(stack) == len(expectedStack)
-//		if match {
-//			for i, s := range stack {
-//				match = match && (s.Cmp(&expectedStack[i]) == 0)
-//			}
-//		}
-//		match = match && (success == test.Expect.Success)
-//
-//		if !match {
-//			fmt.Printf("Instructions: \n%v\n", test.Code.Asm)
-//			fmt.Printf("Expected: success=%v, stack=%v\n", test.Expect.Success, toStrings(expectedStack))
-//			fmt.Printf("Got:      success=%v, stack=%v\n\n", success, toStrings(stack))
-//			fmt.Printf("Hint: %v\n\n", test.Hint)
-//			fmt.Printf("Progress: %v/%v\n\n", index, len(payload))
-//			log.Fatal("Stack mismatch")
-//		}
-//	}
-//}
+// runFrame executes the code of contract with a fresh memory and stack.
+// A read-only frame stays read-only, whatever readOnly is.
+func (evm *EVM) runFrame(contract *Contract, readOnly bool) ([]byte, error) {
+	if readOnly && !evm.readOnly {
+		evm.readOnly = true
+		defer func() { evm.readOnly = false }()
+	}
+	scope := &ScopeContext{
+		Memory:   NewMemory(),
+		Stack:    newstack(),
+		Contract: contract,
+	}
+	defer returnStack(scope.Stack)
+	return evm.run(scope)
+}
@@ -1,7 +1,8 @@
 
-package main
+package evm
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -18,10 +19,33 @@ type testCase struct {
 		Bin string
 		Asm string
 	}
+	Tx struct {
+		To       string
+		From     string
+		Origin   string
+		Gasprice string
+		Value    string
+		Data     string
+	}
+	Block struct {
+		Coinbase   string
+		Timestamp  string
+		Number     string
+		Difficulty string
+		Gaslimit   string
+		Basefee    string
+		Chainid    string
+	}
+	State  GenesisAlloc
 	Expect struct {
 		Stack   []string
 		Success bool
 		Return  string
+		Logs    []struct {
+			Address string
+			Data    string
+			Topics  []string
+		}
 	}
 }
 
@@ -603,73 +627,73 @@ func Test_95_Sha3(t *testing.T) {
 
  
 func Test_96_Address(t *testing.T) {
-	payload := []byte(`{"Name":"ADDRESS","Hint":"Read \"Transaction\" section of the course learning materials. Change your evm function parameters list to include transaction data","Code":{"Bin":"30","Asm":"ADDRESS"},"Expect":{"Stack":["0x1000000000000000000000000000000000000aaa"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"ADDRESS","Hint":"Read \"Transaction\" section of the course learning materials. Change your evm function parameters list to include transaction data","Code":{"Bin":"30","Asm":"ADDRESS"},"Tx":{"to":"0x1000000000000000000000000000000000000aaa"},"Expect":{"Stack":["0x1000000000000000000000000000000000000aaa"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 96, payload)
 }
 
  
 func Test_97_Caller(t *testing.T) {
-	payload := []byte(`{"Name":"CALLER","Hint":"Solidity calls this msg.sender","Code":{"Bin":"33","Asm":"CALLER"},"Expect":{"Stack":["0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLER","Hint":"Solidity calls this msg.sender","Code":{"Bin":"33","Asm":"CALLER"},"Tx":{"from":"0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d"},"Expect":{"Stack":["0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 97, payload)
 }
 
  
 func Test_98_Origin(t *testing.T) {
-	payload := []byte(`{"Name":"ORIGIN","Hint":"Solidity calls this tx.origin","Code":{"Bin":"32","Asm":"ORIGIN"},"Expect":{"Stack":["0x1337"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"ORIGIN","Hint":"Solidity calls this tx.origin","Code":{"Bin":"32","Asm":"ORIGIN"},"Tx":{"origin":"0x1337"},"Expect":{"Stack":["0x1337"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 98, payload)
 }
 
  
 func Test_99_Gasprice(t *testing.T) {
-	payload := []byte(`{"Name":"GASPRICE","Hint":"","Code":{"Bin":"3a","Asm":"GASPRICE"},"Expect":{"Stack":["0x99"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"GASPRICE","Hint":"","Code":{"Bin":"3a","Asm":"GASPRICE"},"Tx":{"gasprice":"0x99"},"Expect":{"Stack":["0x99"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 99, payload)
 }
 
  
 func Test_100_Basefee(t *testing.T) {
-	payload := []byte(`{"Name":"BASEFEE","Hint":"","Code":{"Bin":"48","Asm":"BASEFEE"},"Expect":{"Stack":["0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"BASEFEE","Hint":"","Code":{"Bin":"48","Asm":"BASEFEE"},"Block":{"basefee":"0x1"},"Expect":{"Stack":["0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 100, payload)
 }
 
  
 func Test_101_Coinbase(t *testing.T) {
-	payload := []byte(`{"Name":"COINBASE","Hint":"Do not hardcode these numbers, pull them from the test cases","Code":{"Bin":"41","Asm":"COINBASE"},"Expect":{"Stack":["0x777"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"COINBASE","Hint":"Do not hardcode these numbers, pull them from the test cases","Code":{"Bin":"41","Asm":"COINBASE"},"Block":{"coinbase":"0x777"},"Expect":{"Stack":["0x777"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 101, payload)
 }
 
  
 func Test_102_CoinbaseDifferentOne(t *testing.T) {
-	payload := []byte(`{"Name":"COINBASE (different one)","Hint":"Do not hardcode these numbers, pull them from the test cases","Code":{"Bin":"41","Asm":"COINBASE"},"Expect":{"Stack":["0x888"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"COINBASE (different one)","Hint":"Do not hardcode these numbers, pull them from the test cases","Code":{"Bin":"41","Asm":"COINBASE"},"Block":{"coinbase":"0x888"},"Expect":{"Stack":["0x888"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 102, payload)
 }
 
  
 func Test_103_Timestamp(t *testing.T) {
-	payload := []byte(`{"Name":"TIMESTAMP","Hint":"Solidity calls this block.timestamp","Code":{"Bin":"42","Asm":"TIMESTAMP"},"Expect":{"Stack":["0xe4e1c1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"TIMESTAMP","Hint":"Solidity calls this block.timestamp","Code":{"Bin":"42","Asm":"TIMESTAMP"},"Block":{"timestamp":"0xe4e1c1"},"Expect":{"Stack":["0xe4e1c1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 103, payload)
 }
 
  
 func Test_104_Number(t *testing.T) {
-	payload := []byte(`{"Name":"NUMBER","Hint":"Solidity calls this block.number","Code":{"Bin":"43","Asm":"NUMBER"},"Expect":{"Stack":["0x1000001"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"NUMBER","Hint":"Solidity calls this block.number","Code":{"Bin":"43","Asm":"NUMBER"},"Block":{"number":"0x1000001"},"Expect":{"Stack":["0x1000001"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 104, payload)
 }
 
  
 func Test_105_Difficulty(t *testing.T) {
-	payload := []byte(`{"Name":"DIFFICULTY","Hint":"Also known as PREVRANDAO, not used in these test cases yet","Code":{"Bin":"44","Asm":"DIFFICULTY"},"Expect":{"Stack":["0x20000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"DIFFICULTY","Hint":"Also known as PREVRANDAO, not used in these test cases yet","Code":{"Bin":"44","Asm":"DIFFICULTY"},"Block":{"difficulty":"0x20000"},"Expect":{"Stack":["0x20000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 105, payload)
 }
 
  
 func Test_106_Gaslimit(t *testing.T) {
-	payload := []byte(`{"Name":"GASLIMIT","Hint":"","Code":{"Bin":"45","Asm":"GASLIMIT"},"Expect":{"Stack":["0xffffffffffff"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"GASLIMIT","Hint":"","Code":{"Bin":"45","Asm":"GASLIMIT"},"Block":{"gaslimit":"0xffffffffffff"},"Expect":{"Stack":["0xffffffffffff"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 106, payload)
 }
 
  
 func Test_107_Chainid(t *testing.T) {
-	payload := []byte(`{"Name":"CHAINID","Hint":"","Code":{"Bin":"46","Asm":"CHAINID"},"Expect":{"Stack":["0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CHAINID","Hint":"","Code":{"Bin":"46","Asm":"CHAINID"},"Block":{"chainid":"0x1"},"Expect":{"Stack":["0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 107, payload)
 }
 
@@ -681,7 +705,7 @@ func Test_108_Blockhash(t *testing.T) {
 
  
 func Test_109_Balance(t *testing.T) {
-	payload := []byte(`{"Name":"BALANCE","Hint":"Read \"State\" section of the course learning materials. Modify your evm function to take state as one of the arguments, or turn it into a class","Code":{"Bin":"731e79b045dc29eae9fdc69673c9dcd7c53e5e159d31","Asm":"PUSH20 0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d\nBALANCE"},"Expect":{"Stack":["0x100"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"BALANCE","Hint":"Read \"State\" section of the course learning materials. Modify your evm function to take state as one of the arguments, or turn it into a class","Code":{"Bin":"731e79b045dc29eae9fdc69673c9dcd7c53e5e159d31","Asm":"PUSH20 0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d\nBALANCE"},"State":{"0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d":{"balance":"0x100"}},"Expect":{"Stack":["0x100"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 109, payload)
 }
 
@@ -693,25 +717,25 @@ func Test_110_BalanceEmpty(t *testing.T) {
 
  
 func Test_111_Callvalue(t *testing.T) {
-	payload := []byte(`{"Name":"CALLVALUE","Hint":"Read \"Calls\" section of the course learning materials. Solidity calls this msg.value, it is amount of wei sent as part of this transaction","Code":{"Bin":"34","Asm":"CALLVALUE"},"Expect":{"Stack":["0x1000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLVALUE","Hint":"Read \"Calls\" section of the course learning materials. Solidity calls this msg.value, it is amount of wei sent as part of this transaction","Code":{"Bin":"34","Asm":"CALLVALUE"},"Tx":{"value":"0x1000"},"Expect":{"Stack":["0x1000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 111, payload)
 }
 
  
 func Test_112_Calldataload(t *testing.T) {
-	payload := []byte(`{"Name":"CALLDATALOAD","Hint":"Read \"Calls\" section of the course learning materials. Calldata is an array of bytes sent to the evm function","Code":{"Bin":"600035","Asm":"PUSH1 0\nCALLDATALOAD"},"Expect":{"Stack":["0x102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLDATALOAD","Hint":"Read \"Calls\" section of the course learning materials. Calldata is an array of bytes sent to the evm function","Code":{"Bin":"600035","Asm":"PUSH1 0\nCALLDATALOAD"},"Tx":{"data":"000102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"},"Expect":{"Stack":["0x102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 112, payload)
 }
 
  
 func Test_113_CalldataloadTail(t *testing.T) {
-	payload := []byte(`{"Name":"CALLDATALOAD (tail)","Hint":"Overflow bytes filled with zeros","Code":{"Bin":"601f35","Asm":"PUSH1 31\nCALLDATALOAD"},"Expect":{"Stack":["0xff00000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLDATALOAD (tail)","Hint":"Overflow bytes filled with zeros","Code":{"Bin":"601f35","Asm":"PUSH1 31\nCALLDATALOAD"},"Tx":{"data":"000102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"},"Expect":{"Stack":["0xff00000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 113, payload)
 }
 
  
 func Test_114_Calldatasize(t *testing.T) {
-	payload := []byte(`{"Name":"CALLDATASIZE","Hint":"Size (in bytes) of calldata buffer","Code":{"Bin":"36","Asm":"CALLDATASIZE"},"Expect":{"Stack":["0x20"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLDATASIZE","Hint":"Size (in bytes) of calldata buffer","Code":{"Bin":"36","Asm":"CALLDATASIZE"},"Tx":{"data":"000102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"},"Expect":{"Stack":["0x20"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 114, payload)
 }
 
@@ -723,13 +747,13 @@ func Test_115_CalldatasizeNoData(t *testing.T) {
 
  
 func Test_116_Calldatacopy(t *testing.T) {
-	payload := []byte(`{"Name":"CALLDATACOPY","Hint":"Copy 32-byte chunk of calldata into memory. Do not forget to update MSIZE after CALLDATACOPY","Code":{"Bin":"60206000600037600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nCALLDATACOPY\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0x102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLDATACOPY","Hint":"Copy 32-byte chunk of calldata into memory. Do not forget to update MSIZE after CALLDATACOPY","Code":{"Bin":"60206000600037600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nCALLDATACOPY\nPUSH1 0\nMLOAD"},"Tx":{"data":"000102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"},"Expect":{"Stack":["0x102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 116, payload)
 }
 
  
 func Test_117_CalldatacopyTail(t *testing.T) {
-	payload := []byte(`{"Name":"CALLDATACOPY (tail)","Hint":"Overflow bytes filled with zeros","Code":{"Bin":"6001601f600037600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nCALLDATACOPY\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0xff00000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALLDATACOPY (tail)","Hint":"Overflow bytes filled with zeros","Code":{"Bin":"6001601f600037600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nCALLDATACOPY\nPUSH1 0\nMLOAD"},"Tx":{"data":"000102030405060708090a0b0c0d0e0f00112233445566778899aabbccddeeff"},"Expect":{"Stack":["0xff00000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 117, payload)
 }
 
@@ -765,19 +789,19 @@ func Test_122_ExtcodesizeEmpty(t *testing.T) {
 
  
 func Test_123_Extcodesize(t *testing.T) {
-	payload := []byte(`{"Name":"EXTCODESIZE","Hint":"Read \"State\" section of the course learning materials","Code":{"Bin":"731000000000000000000000000000000000000aaa3b","Asm":"PUSH20 0x1000000000000000000000000000000000000aaa\nEXTCODESIZE"},"Expect":{"Stack":["0x2"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"EXTCODESIZE","Hint":"Read \"State\" section of the course learning materials","Code":{"Bin":"731000000000000000000000000000000000000aaa3b","Asm":"PUSH20 0x1000000000000000000000000000000000000aaa\nEXTCODESIZE"},"State":{"0x1000000000000000000000000000000000000aaa":{"code":{"asm":"PUSH1 1","bin":"6001"}}},"Expect":{"Stack":["0x2"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 123, payload)
 }
 
  
 func Test_124_Extcodecopy(t *testing.T) {
-	payload := []byte(`{"Name":"EXTCODECOPY","Hint":"","Code":{"Bin":"602060006000731000000000000000000000000000000000000aaa3c600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000aaa\nEXTCODECOPY\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0x6001000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"EXTCODECOPY","Hint":"","Code":{"Bin":"602060006000731000000000000000000000000000000000000aaa3c600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000aaa\nEXTCODECOPY\nPUSH1 0\nMLOAD"},"State":{"0x1000000000000000000000000000000000000aaa":{"code":{"asm":null,"bin":"6001"}}},"Expect":{"Stack":["0x6001000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 124, payload)
 }
 
  
 func Test_125_Extcodehash(t *testing.T) {
-	payload := []byte(`{"Name":"EXTCODEHASH","Hint":"Use the same library you used for SHA3 opcode","Code":{"Bin":"731000000000000000000000000000000000000aaa3f","Asm":"PUSH20 0x1000000000000000000000000000000000000aaa\nEXTCODEHASH"},"Expect":{"Stack":["0x29045a592007d0c246ef02c2223570da9522d0cf0f73282c79a1bc8f0bb2c238"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"EXTCODEHASH","Hint":"Use the same library you used for SHA3 opcode","Code":{"Bin":"731000000000000000000000000000000000000aaa3f","Asm":"PUSH20 0x1000000000000000000000000000000000000aaa\nEXTCODEHASH"},"State":{"0x1000000000000000000000000000000000000aaa":{"code":{"asm":null,"bin":"FFFFFFFF"}}},"Expect":{"Stack":["0x29045a592007d0c246ef02c2223570da9522d0cf0f73282c79a1bc8f0bb2c238"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 125, payload)
 }
 
@@ -789,7 +813,7 @@ func Test_126_ExtcodehashEmpty(t *testing.T) {
 
  
 func Test_127_Selfbalance(t *testing.T) {
-	payload := []byte(`{"Name":"SELFBALANCE","Hint":"","Code":{"Bin":"47","Asm":"SELFBALANCE"},"Expect":{"Stack":["0x200"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"SELFBALANCE","Hint":"","Code":{"Bin":"47","Asm":"SELFBALANCE"},"Tx":{"to":"0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d"},"State":{"0x1e79b045dc29eae9fdc69673c9dcd7c53e5e159d":{"balance":"0x200"}},"Expect":{"Stack":["0x200"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 127, payload)
 }
 
@@ -813,31 +837,31 @@ func Test_130_SloadEmpty(t *testing.T) {
 
  
 func Test_131_Log0(t *testing.T) {
-	payload := []byte(`{"Name":"LOG0","Hint":"Make evm function return array of logs, modify the testing code to assert that the logs match","Code":{"Bin":"60aa6000526001601fa0","Asm":"PUSH1 0xaa\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nLOG0"},"Expect":{"Stack":null,"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"LOG0","Hint":"Make evm function return array of logs, modify the testing code to assert that the logs match","Code":{"Bin":"60aa6000526001601fa0","Asm":"PUSH1 0xaa\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nLOG0"},"Tx":{"to":"0x1000000000000000000000000000000000000001"},"Expect":{"Stack":null,"Success":true,"Return":"","Logs":[{"address":"0x1000000000000000000000000000000000000001","data":"aa","topics":[]}]},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 131, payload)
 }
 
  
 func Test_132_Log1(t *testing.T) {
-	payload := []byte(`{"Name":"LOG1","Hint":"Make evm function return array of logs, modify the testing code to assert that the logs match","Code":{"Bin":"60bb6000527f11111111111111111111111111111111111111111111111111111111111111116001601fa1","Asm":"PUSH1 0xbb\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH1 1\nPUSH1 31\nLOG1"},"Expect":{"Stack":null,"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"LOG1","Hint":"Make evm function return array of logs, modify the testing code to assert that the logs match","Code":{"Bin":"60bb6000527f11111111111111111111111111111111111111111111111111111111111111116001601fa1","Asm":"PUSH1 0xbb\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH1 1\nPUSH1 31\nLOG1"},"Tx":{"to":"0x1000000000000000000000000000000000000001"},"Expect":{"Stack":null,"Success":true,"Return":"","Logs":[{"address":"0x1000000000000000000000000000000000000001","data":"bb","topics":["0x1111111111111111111111111111111111111111111111111111111111111111"]}]},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 132, payload)
 }
 
  
 func Test_133_Log2(t *testing.T) {
-	payload := []byte(`{"Name":"LOG2","Hint":"Use the same code to handle LOG1...LOG4 opcodes","Code":{"Bin":"60cc6000527f11111111111111111111111111111111111111111111111111111111111111117f22222222222222222222222222222222222222222222222222222222222222226001601fa2","Asm":"PUSH1 0xcc\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH32 0x2222222222222222222222222222222222222222222222222222222222222222\nPUSH1 1\nPUSH1 31\nLOG2"},"Expect":{"Stack":null,"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"LOG2","Hint":"Use the same code to handle LOG1...LOG4 opcodes","Code":{"Bin":"60cc6000527f11111111111111111111111111111111111111111111111111111111111111117f22222222222222222222222222222222222222222222222222222222222222226001601fa2","Asm":"PUSH1 0xcc\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH32 0x2222222222222222222222222222222222222222222222222222222222222222\nPUSH1 1\nPUSH1 31\nLOG2"},"Tx":{"to":"0x1000000000000000000000000000000000000001"},"Expect":{"Stack":null,"Success":true,"Return":"","Logs":[{"address":"0x1000000000000000000000000000000000000001","data":"cc","topics":["0x2222222222222222222222222222222222222222222222222222222222222222","0x1111111111111111111111111111111111111111111111111111111111111111"]}]},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 133, payload)
 }
 
  
 func Test_134_Log3(t *testing.T) {
-	payload := []byte(`{"Name":"LOG3","Hint":"N = OPCODE - LOG0, pop N items from the stack as topics","Code":{"Bin":"60dd6000527f11111111111111111111111111111111111111111111111111111111111111117f22222222222222222222222222222222222222222222222222222222222222227f33333333333333333333333333333333333333333333333333333333333333336001601fa3","Asm":"PUSH1 0xdd\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH32 0x2222222222222222222222222222222222222222222222222222222222222222\nPUSH32 0x3333333333333333333333333333333333333333333333333333333333333333\nPUSH1 1\nPUSH1 31\nLOG3"},"Expect":{"Stack":null,"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"LOG3","Hint":"N = OPCODE - LOG0, pop N items from the stack as topics","Code":{"Bin":"60dd6000527f11111111111111111111111111111111111111111111111111111111111111117f22222222222222222222222222222222222222222222222222222222222222227f33333333333333333333333333333333333333333333333333333333333333336001601fa3","Asm":"PUSH1 0xdd\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH32 0x2222222222222222222222222222222222222222222222222222222222222222\nPUSH32 0x3333333333333333333333333333333333333333333333333333333333333333\nPUSH1 1\nPUSH1 31\nLOG3"},"Tx":{"to":"0x1000000000000000000000000000000000000001"},"Expect":{"Stack":null,"Success":true,"Return":"","Logs":[{"address":"0x1000000000000000000000000000000000000001","data":"dd","topics":["0x3333333333333333333333333333333333333333333333333333333333333333","0x2222222222222222222222222222222222222222222222222222222222222222","0x1111111111111111111111111111111111111111111111111111111111111111"]}]},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 134, payload)
 }
 
  
 func Test_135_Log4(t *testing.T) {
-	payload := []byte(`{"Name":"LOG4","Hint":"Refactoring code is always a good idea. Your code will become cleaner, and the tests will catch if something breaks","Code":{"Bin":"60ee6000527f11111111111111111111111111111111111111111111111111111111111111117f22222222222222222222222222222222222222222222222222222222222222227f33333333333333333333333333333333333333333333333333333333333333337f44444444444444444444444444444444444444444444444444444444444444446001601fa4","Asm":"PUSH1 0xee\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH32 0x2222222222222222222222222222222222222222222222222222222222222222\nPUSH32 0x3333333333333333333333333333333333333333333333333333333333333333\nPUSH32 0x4444444444444444444444444444444444444444444444444444444444444444\nPUSH1 1\nPUSH1 31\nLOG4"},"Expect":{"Stack":null,"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"LOG4","Hint":"Refactoring code is always a good idea. Your code will become cleaner, and the tests will catch if something breaks","Code":{"Bin":"60ee6000527f11111111111111111111111111111111111111111111111111111111111111117f22222222222222222222222222222222222222222222222222222222222222227f33333333333333333333333333333333333333333333333333333333333333337f44444444444444444444444444444444444444444444444444444444444444446001601fa4","Asm":"PUSH1 0xee\nPUSH1 0\nMSTORE\nPUSH32 0x1111111111111111111111111111111111111111111111111111111111111111\nPUSH32 0x2222222222222222222222222222222222222222222222222222222222222222\nPUSH32 0x3333333333333333333333333333333333333333333333333333333333333333\nPUSH32 0x4444444444444444444444444444444444444444444444444444444444444444\nPUSH1 1\nPUSH1 31\nLOG4"},"Tx":{"to":"0x1000000000000000000000000000000000000001"},"Expect":{"Stack":null,"Success":true,"Return":"","Logs":[{"address":"0x1000000000000000000000000000000000000001","data":"ee","topics":["0x4444444444444444444444444444444444444444444444444444444444444444","0x3333333333333333333333333333333333333333333333333333333333333333","0x2222222222222222222222222222222222222222222222222222222222222222","0x1111111111111111111111111111111111111111111111111111111111111111"]}]},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 135, payload)
 }
 
@@ -855,19 +879,19 @@ func Test_137_Revert(t *testing.T) {
 
  
 func Test_138_Call(t *testing.T) {
-	payload := []byte(`{"Name":"CALL","Hint":"Read \"Calls and Returns\" section of the course learning materials. Recursively call evm function from itself when handing this opcode","Code":{"Bin":"6001601f600060006000731000000000000000000000000000000000000c426000f1600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0x42","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALL","Hint":"Read \"Calls and Returns\" section of the course learning materials. Recursively call evm function from itself when handing this opcode","Code":{"Bin":"6001601f600060006000731000000000000000000000000000000000000c426000f1600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPUSH1 0\nMLOAD"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"PUSH1 0x42\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nRETURN","bin":"60426000526001601ff3"}}},"Expect":{"Stack":["0x42","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 138, payload)
 }
 
  
 func Test_139_CallReturnsAddress(t *testing.T) {
-	payload := []byte(`{"Name":"CALL (returns address)","Hint":"In the inner context, the CALLER is the contract we are sending the initial transaction to","Code":{"Bin":"60206000600060006000731000000000000000000000000000000000000c426000f1600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0x1000000000000000000000000000000000000aaa","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALL (returns address)","Hint":"In the inner context, the CALLER is the contract we are sending the initial transaction to","Code":{"Bin":"60206000600060006000731000000000000000000000000000000000000c426000f1600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPUSH1 0\nMLOAD"},"Tx":{"to":"0x1000000000000000000000000000000000000aaa"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"CALLER\nPUSH1 0\nMSTORE\nPUSH1 32\nPUSH1 0\nRETURN","bin":"3360005260206000f3"}}},"Expect":{"Stack":["0x1000000000000000000000000000000000000aaa","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 139, payload)
 }
 
  
 func Test_140_CallReverts(t *testing.T) {
-	payload := []byte(`{"Name":"CALL (reverts)","Hint":"Reverts can also return data","Code":{"Bin":"6001601f600060006000731000000000000000000000000000000000000c426000f1600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0x42","0x0"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CALL (reverts)","Hint":"Reverts can also return data","Code":{"Bin":"6001601f600060006000731000000000000000000000000000000000000c426000f1600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPUSH1 0\nMLOAD"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"PUSH1 0x42\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nREVERT","bin":"60426000526001601ffd"}}},"Expect":{"Stack":["0x42","0x0"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 140, payload)
 }
 
@@ -879,55 +903,55 @@ func Test_141_ReturndatasizeEmpty(t *testing.T) {
 
  
 func Test_142_Returndatasize(t *testing.T) {
-	payload := []byte(`{"Name":"RETURNDATASIZE","Hint":"","Code":{"Bin":"60006000600060006000731000000000000000000000000000000000000c426000f1503d","Asm":"PUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPOP\nRETURNDATASIZE"},"Expect":{"Stack":["0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"RETURNDATASIZE","Hint":"","Code":{"Bin":"60006000600060006000731000000000000000000000000000000000000c426000f1503d","Asm":"PUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPOP\nRETURNDATASIZE"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"PUSH1 0x42\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nRETURN","bin":"60426000526001601ff3"}}},"Expect":{"Stack":["0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 142, payload)
 }
 
  
 func Test_143_Returndatacopy(t *testing.T) {
-	payload := []byte(`{"Name":"RETURNDATACOPY","Hint":"","Code":{"Bin":"6001601f600060006000731000000000000000000000000000000000000c426000f1506001600060ff3e60ff51","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPOP\nPUSH1 1\nPUSH1 0\nPUSH1 0xff\nRETURNDATACOPY\nPUSH1 0xff\nMLOAD"},"Expect":{"Stack":["0x4200000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"RETURNDATACOPY","Hint":"","Code":{"Bin":"6001601f600060006000731000000000000000000000000000000000000c426000f1506001600060ff3e60ff51","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nCALL\nPOP\nPUSH1 1\nPUSH1 0\nPUSH1 0xff\nRETURNDATACOPY\nPUSH1 0xff\nMLOAD"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"PUSH1 0x42\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nRETURN","bin":"60426000526001601ff3"}}},"Expect":{"Stack":["0x4200000000000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 143, payload)
 }
 
  
 func Test_144_Delegatecall(t *testing.T) {
-	payload := []byte(`{"Name":"DELEGATECALL","Hint":"Like CALL, but keep the transaction data (from, origin, address) and use the code from the other account","Code":{"Bin":"600080808073dddddddddddddddddddddddddddddddddddddddd5af4600054","Asm":"PUSH1 0\nDUP1\nDUP1\nDUP1\nPUSH20 0xdddddddddddddddddddddddddddddddddddddddd\nGAS\nDELEGATECALL\nPUSH1 0\nSLOAD"},"Expect":{"Stack":["0x1000000000000000000000000000000000000aaa","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"DELEGATECALL","Hint":"Like CALL, but keep the transaction data (from, origin, address) and use the code from the other account","Code":{"Bin":"600080808073dddddddddddddddddddddddddddddddddddddddd5af4600054","Asm":"PUSH1 0\nDUP1\nDUP1\nDUP1\nPUSH20 0xdddddddddddddddddddddddddddddddddddddddd\nGAS\nDELEGATECALL\nPUSH1 0\nSLOAD"},"Tx":{"to":"0x1000000000000000000000000000000000000aaa"},"State":{"0xdddddddddddddddddddddddddddddddddddddddd":{"code":{"asm":"ADDRESS\nPUSH1 0\nSSTORE","bin":"30600055"}}},"Expect":{"Stack":["0x1000000000000000000000000000000000000aaa","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 144, payload)
 }
 
  
 func Test_145_Staticcall(t *testing.T) {
-	payload := []byte(`{"Name":"STATICCALL","Hint":"Like CALL, but disable state modifications","Code":{"Bin":"6001601f60006000731000000000000000000000000000000000000c426000fa600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nSTATICCALL\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0x42","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"STATICCALL","Hint":"Like CALL, but disable state modifications","Code":{"Bin":"6001601f60006000731000000000000000000000000000000000000c426000fa600051","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nSTATICCALL\nPUSH1 0\nMLOAD"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"PUSH1 0x42\nPUSH1 0\nMSTORE\nPUSH1 1\nPUSH1 31\nRETURN","bin":"60426000526001601ff3"}}},"Expect":{"Stack":["0x42","0x1"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 145, payload)
 }
 
  
 func Test_146_StaticcallRevertsOnWrite(t *testing.T) {
-	payload := []byte(`{"Name":"STATICCALL (reverts on write)","Hint":"Use a flag to tell the evm function whenever the context is writeable (CALL) or not (STATICCALL)","Code":{"Bin":"6001601f60006000731000000000000000000000000000000000000c426000fa","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nSTATICCALL"},"Expect":{"Stack":["0x0"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"STATICCALL (reverts on write)","Hint":"Use a flag to tell the evm function whenever the context is writeable (CALL) or not (STATICCALL)","Code":{"Bin":"6001601f60006000731000000000000000000000000000000000000c426000fa","Asm":"PUSH1 1\nPUSH1 31\nPUSH1 0\nPUSH1 0\nPUSH20 0x1000000000000000000000000000000000000c42\nPUSH1 0\nSTATICCALL"},"State":{"0x1000000000000000000000000000000000000c42":{"code":{"asm":"PUSH1 0x42\nPUSH1 0\nSSTORE","bin":"6042600055"}}},"Expect":{"Stack":["0x0"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 146, payload)
 }
 
  
 func Test_147_CreateEmpty(t *testing.T) {
-	payload := []byte(`{"Name":"CREATE (empty)","Hint":"Read \"Creating new contracts\" section of the course learning materials. This code creates a new empty account with balance 9","Code":{"Bin":"600060006009f031","Asm":"PUSH1 0\nPUSH1 0\nPUSH1 9\nCREATE\nBALANCE"},"Expect":{"Stack":["0x9"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CREATE (empty)","Hint":"Read \"Creating new contracts\" section of the course learning materials. This code creates a new empty account with balance 9","Code":{"Bin":"600060006009f031","Asm":"PUSH1 0\nPUSH1 0\nPUSH1 9\nCREATE\nBALANCE"},"Tx":{"to":"0x9bbfed6889322e016e0a02ee459d306fc19545d8"},"Expect":{"Stack":["0x9"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 147, payload)
 }
 
  
 func Test_148_CreateWith4xFf(t *testing.T) {
-	payload := []byte(`{"Name":"CREATE (with 4x FF)","Hint":"Read \"Creating new contracts\" section of the course learning materials. CALL with the given code, store the returned bytes as new contracts bytecode","Code":{"Bin":"6020600060006c63ffffffff6000526004601cf3600052600d60136000f03c600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nPUSH13 0x63FFFFFFFF6000526004601CF3\nPUSH1 0\nMSTORE\nPUSH1 13\nPUSH1 19\nPUSH1 0\nCREATE\nEXTCODECOPY\nPUSH1 0\nMLOAD"},"Expect":{"Stack":["0xffffffff00000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CREATE (with 4x FF)","Hint":"Read \"Creating new contracts\" section of the course learning materials. CALL with the given code, store the returned bytes as new contracts bytecode","Code":{"Bin":"6020600060006c63ffffffff6000526004601cf3600052600d60136000f03c600051","Asm":"PUSH1 32\nPUSH1 0\nPUSH1 0\nPUSH13 0x63FFFFFFFF6000526004601CF3\nPUSH1 0\nMSTORE\nPUSH1 13\nPUSH1 19\nPUSH1 0\nCREATE\nEXTCODECOPY\nPUSH1 0\nMLOAD"},"Tx":{"to":"0x9bbfed6889322e016e0a02ee459d306fc19545d8"},"Expect":{"Stack":["0xffffffff00000000000000000000000000000000000000000000000000000000"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 148, payload)
 }
 
  
 func Test_149_CreateReverts(t *testing.T) {
-	payload := []byte(`{"Name":"CREATE (reverts)","Hint":"No address when constructor code reverts","Code":{"Bin":"6c63ffffffff6000526004601cfd600052600d60136000f0","Asm":"PUSH13 0x63FFFFFFFF6000526004601CFD\nPUSH1 0\nMSTORE\nPUSH1 13\nPUSH1 19\nPUSH1 0\nCREATE"},"Expect":{"Stack":["0x0"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"CREATE (reverts)","Hint":"No address when constructor code reverts","Code":{"Bin":"6c63ffffffff6000526004601cfd600052600d60136000f0","Asm":"PUSH13 0x63FFFFFFFF6000526004601CFD\nPUSH1 0\nMSTORE\nPUSH1 13\nPUSH1 19\nPUSH1 0\nCREATE"},"Tx":{"to":"0x9bbfed6889322e016e0a02ee459d306fc19545d8"},"Expect":{"Stack":["0x0"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 149, payload)
 }
 
  
 func Test_150_Selfdestruct(t *testing.T) {
-	payload := []byte(`{"Name":"SELFDESTRUCT","Hint":"Note that for simplicity, this opcode should delete the account from the state. In the real EVM this happens only after the transaction has been processed, but that would overcomplicate these tests","Code":{"Bin":"60008080808073dead00000000000000000000000000000000dead5af15073a1c300000000000000000000000000000000a1c33173dead00000000000000000000000000000000dead3b","Asm":"PUSH1 0\nDUP1\nDUP1\nDUP1\nDUP1\nPUSH20 0xdead00000000000000000000000000000000dead\nGAS\nCALL\nPOP\nPUSH20 0xa1c300000000000000000000000000000000a1c3\nBALANCE\nPUSH20 0xdead00000000000000000000000000000000dead\nEXTCODESIZE"},"Expect":{"Stack":["0x0","0x7"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
+	payload := []byte(`{"Name":"SELFDESTRUCT","Hint":"Note that for simplicity, this opcode should delete the account from the state. In the real EVM this happens only after the transaction has been processed, but that would overcomplicate these tests","Code":{"Bin":"60008080808073dead00000000000000000000000000000000dead5af15073a1c300000000000000000000000000000000a1c33173dead00000000000000000000000000000000dead3b","Asm":"PUSH1 0\nDUP1\nDUP1\nDUP1\nDUP1\nPUSH20 0xdead00000000000000000000000000000000dead\nGAS\nCALL\nPOP\nPUSH20 0xa1c300000000000000000000000000000000a1c3\nBALANCE\nPUSH20 0xdead00000000000000000000000000000000dead\nEXTCODESIZE"},"State":{"0xdead00000000000000000000000000000000dead":{"balance":"0x7","code":{"asm":"PUSH20 0xa1c300000000000000000000000000000000a1c3\nSELFDESTRUCT","bin":"73a1c300000000000000000000000000000000a1c3ff"}}},"Expect":{"Stack":["0x0","0x7"],"Success":true,"Return":""},"FnName":"","Index":0,"Payload":""}`)
 	runTest(t, 150, payload)
 }
 
@@ -954,10 +978,39 @@ func runTest(t *testing.T, index int, payload []byte) {
 		expectedStack = append(expectedStack, *i)
 	}
 
-	// Note: as the test cases get more complex, you'll need to modify this
-	// to pass down more arguments to the evm function and return more than
-	// just the stack.
-	success, stack := evm(bin)
+	blockCtx := BlockContext{
+		CanTransfer: func(StateDB, Address, *uint256.Int) bool { return true },
+		Transfer:    mintingTransfer,
+		Coinbase:   HexToAddress(test.Block.Coinbase),
+		GasLimit:   hexToInt(test.Block.Gaslimit).Uint64(),
+		Number:     hexToInt(test.Block.Number).Uint64(),
+		Time:       hexToInt(test.Block.Timestamp).Uint64(),
+		Difficulty: hexToInt(test.Block.Difficulty),
+		BaseFee:    hexToInt(test.Block.Basefee),
+	}
+	txCtx := TxContext{
+		Origin:   HexToAddress(test.Tx.Origin),
+		GasPrice: hexToInt(test.Tx.Gasprice),
+	}
+	config := Config{
+		ChainID:           hexToInt(test.Block.Chainid).Uint64(),
+		EagerSelfDestruct: true,
+		NoGasMetering:     true,
+	}
+
+	contract := NewContract(
+		HexToAddress(test.Tx.From),
+		HexToAddress(test.Tx.To),
+		hexToInt(test.Tx.Value),
+		bin,
+		fromHex(test.Tx.Data),
+		blockCtx.GasLimit,
+	)
+
+	statedb := NewMemoryStateDBFromAlloc(test.State)
+
+	result := NewEVM(blockCtx, txCtx, statedb, config).RunContract(contract)
+	success, stack := result.Success, result.Stack
 
 	match := len(stack) == len(expectedStack)
 	if match {
@@ -966,17 +1019,65 @@ func runTest(t *testing.T, index int, payload []byte) {
 		}
 	}
 	match = match && (success == test.Expect.Success)
+	if test.Expect.Return != "" {
+		match = match && bytes.Equal(result.ReturnData, fromHex(test.Expect.Return))
+	}
+
+	if test.Expect.Logs != nil {
+		logsMatch := len(result.Logs) == len(test.Expect.Logs)
+		for i := 0; logsMatch && i < len(result.Logs); i++ {
+			want, got := test.Expect.Logs[i], result.Logs[i]
+			logsMatch = got.Address == HexToAddress(want.Address) &&
+				bytes.Equal(got.Data, fromHex(want.Data)) &&
+				len(got.Topics) == len(want.Topics)
+			for j := 0; logsMatch && j < len(want.Topics); j++ {
+				logsMatch = got.Topics[j] == HexToHash(want.Topics[j])
+			}
+		}
+		if !logsMatch {
+			fmt.Printf("Instructions: \n%v\n", test.Code.Asm)
+			fmt.Printf("Expected logs: %+v\n", test.Expect.Logs)
+			fmt.Printf("Got logs:      %v\n\n", toLogStrings(result.Logs))
+			fmt.Printf("Progress: %v/%v\n\n", index, len(payload))
+			log.Fatal("Logs mismatch")
+		}
+	}
 
 	if !match {
 		fmt.Printf("Instructions: \n%v\n", test.Code.Asm)
 		fmt.Printf("Expected: success=%v, stack=%v\n", test.Expect.Success, toStrings(expectedStack))
 		fmt.Printf("Got:      success=%v, stack=%v\n\n", success, toStrings(stack))
+		if test.Expect.Return != "" {
+			fmt.Printf("Expected return: %v\n", test.Expect.Return)
+			fmt.Printf("Got return:      %x\n\n", result.ReturnData)
+		}
+		if result.Err != nil {
+			fmt.Printf("Error: %v\n\n", result.Err)
+		}
 		fmt.Printf("Hint: %v\n\n", test.Hint)
 		fmt.Printf("Progress: %v/%v\n\n", index, len(payload))
 		log.Fatal("Stack mismatch")
 	}
 }
 
+// mintingTransfer credits the recipient without debiting the sender. The
+// test cases send value from accounts they never fund.
+func mintingTransfer(db StateDB, sender, recipient Address, amount *uint256.Int) {
+	db.AddBalance(recipient, amount)
+}
+
+func hexToInt(s string) *uint256.Int {
+	return new(uint256.Int).SetBytes(fromHex(s))
+}
+
+func toLogStrings(logs []*Log) []string {
+	var strings []string
+	for _, l := range logs {
+		strings = append(strings, fmt.Sprintf("{Address:%v Data:%x Topics:%v}", l.Address, l.Data, l.Topics))
+	}
+	return strings
+}
+
 func toStrings(stack []uint256.Int) []string {
 	var strings []string
 	for _, s := range stack {
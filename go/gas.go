@@ -0,0 +1,101 @@
+package evm
+
+// Gas cost tiers of the Yellow Paper fee schedule.
+const (
+	gasQuickStep   uint64 = 2
+	gasFastestStep uint64 = 3
+	gasFastStep    uint64 = 5
+	gasMidStep     uint64 = 8
+	gasSlowStep    uint64 = 10
+	gasExtStep     uint64 = 20
+)
+
+const (
+	sha3Gas     uint64 = 30
+	jumpdestGas uint64 = 1
+	createGas   uint64 = 32000
+	// createDataGas is charged per byte of deployed code.
+	createDataGas uint64 = 200
+
+	selfdestructGasEIP150 uint64 = 5000
+)
+
+// Dynamic gas costs, charged per unit of operand size.
+const (
+	// memoryGas and quadCoeffDiv define the memory expansion cost of
+	// words*memoryGas + words*words/quadCoeffDiv.
+	memoryGas    uint64 = 3
+	quadCoeffDiv uint64 = 512
+
+	copyGas         uint64 = 3  // per word copied by *COPY instructions
+	expByteGas      uint64 = 50 // per byte of the EXP exponent (EIP-160)
+	sha3WordGas     uint64 = 6  // per word hashed
+	initCodeWordGas uint64 = 2  // per word of CREATE init code (EIP-3860)
+
+	logGas      uint64 = 375
+	logTopicGas uint64 = 375
+	logDataGas  uint64 = 8 // per byte of log data
+)
+
+// Costs of accessing accounts and storage slots (EIP-2929). An access is
+// cold the first time the transaction makes it, and warm afterwards.
+const (
+	warmStorageReadCostEIP2929   uint64 = 100
+	coldSloadCostEIP2929         uint64 = 2100
+	coldAccountAccessCostEIP2929 uint64 = 2600
+)
+
+// Costs of the CALL family.
+const (
+	// callValueTransferGas is charged for a call that transfers value, and
+	// callStipend of it is given to the callee on top of the gas forwarded
+	callValueTransferGas uint64 = 9000
+	callStipend          uint64 = 2300
+	// callNewAccountGas is charged for sending value to an empty account
+	callNewAccountGas uint64 = 25000
+)
+
+// SSTORE costs and refunds (EIP-2200, EIP-2929, EIP-3529).
+const (
+	// SSTORE fails unless more than sstoreSentryGasEIP2200 is left, so
+	// that the stipend of a value transfer cannot pay for it
+	sstoreSentryGasEIP2200 uint64 = 2300
+	sstoreSetGasEIP2200    uint64 = 20000
+	sstoreResetGasEIP2200  uint64 = 5000
+
+	sstoreClearsScheduleRefundEIP2200 uint64 = 15000
+	// sstoreResetGasEIP2200 - coldSloadCostEIP2929 plus the cost of a
+	// storage key in an access list
+	sstoreClearsScheduleRefundEIP3529 uint64 = 4800
+)
+
+// selfdestructRefundGas is refunded for SELFDESTRUCT before London.
+const selfdestructRefundGas uint64 = 24000
+
+// The refund is capped at the gas used divided by the refund quotient.
+const (
+	refundQuotient        uint64 = 2
+	refundQuotientEIP3529 uint64 = 5
+)
+
+// createBySelfdestructGas is charged when SELFDESTRUCT sends value to an
+// empty account, which creates it (EIP-161).
+const createBySelfdestructGas uint64 = 25000
+
+// Transaction costs, paid before execution starts.
+const (
+	txGas                 uint64 = 21000
+	txGasContractCreation uint64 = 53000 // Homestead
+
+	txDataZeroGas           uint64 = 4
+	txDataNonZeroGasEIP2028 uint64 = 16
+
+	txAccessListAddressGas    uint64 = 2400 // EIP-2930
+	txAccessListStorageKeyGas uint64 = 1900 // EIP-2930
+
+	// txCostFloorPerToken is the minimum a transaction pays per token of
+	// calldata, where a zero byte is one token and any other byte four
+	// (EIP-7623)
+	txCostFloorPerToken   uint64 = 10
+	txTokenPerNonZeroByte uint64 = 4
+)
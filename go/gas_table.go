@@ -0,0 +1,394 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+// memoryGasCost returns the gas for growing mem to newMemSize bytes: the
+// cost of the new size, 3 per word plus words²/512, less what the frame
+// has already paid for its memory.
+func memoryGasCost(mem *Memory, newMemSize uint64) (uint64, error) {
+	if newMemSize == 0 {
+		return 0, nil
+	}
+	// beyond this size the quadratic term overflows uint64; nobody can
+	// pay for that much memory anyway
+	if newMemSize > 0x1FFFFFFFE0 {
+		return 0, ErrGasUintOverflow
+	}
+	newMemSizeWords := toWordSize(newMemSize)
+	newMemSize = newMemSizeWords * 32
+
+	if newMemSize > mem.Len() {
+		square := newMemSizeWords * newMemSizeWords
+		linCoef := newMemSizeWords * memoryGas
+		quadCoef := square / quadCoeffDiv
+		newTotalFee := linCoef + quadCoef
+
+		fee := newTotalFee - mem.lastGasCost
+		mem.lastGasCost = newTotalFee
+		return fee, nil
+	}
+	return 0, nil
+}
+
+// memoryCopierGas returns the gas function of an instruction that copies
+// the number of bytes at stack position stackpos: memory expansion plus
+// copyGas per word.
+func memoryCopierGas(stackpos int) gasFunc {
+	return func(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+		gas, err := memoryGasCost(scope.Memory, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		words, overflow := scope.Stack.Back(stackpos).Uint64WithOverflow()
+		if overflow {
+			return 0, ErrGasUintOverflow
+		}
+		if words, overflow = safeMul(toWordSize(words), copyGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		if gas, overflow = safeAdd(gas, words); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+var (
+	gasCallDataCopy   = memoryCopierGas(2)
+	gasCodeCopy       = memoryCopierGas(2)
+	gasMcopy          = memoryCopierGas(2)
+	gasExtCodeCopy    = memoryCopierGas(3)
+	gasReturnDataCopy = memoryCopierGas(2)
+)
+
+// pureMemoryGascost is the gas function of instructions whose only dynamic
+// cost is memory expansion.
+func pureMemoryGascost(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	return memoryGasCost(scope.Memory, memorySize)
+}
+
+var (
+	gasReturn  = pureMemoryGascost
+	gasRevert  = pureMemoryGascost
+	gasMLoad   = pureMemoryGascost
+	gasMStore8 = pureMemoryGascost
+	gasMStore  = pureMemoryGascost
+	gasCreate  = pureMemoryGascost
+)
+
+// callGas returns the gas a call forwards: what was requested, capped at
+// all but one 64th of the gas left after paying base (EIP-150).
+// If base is more than is available, the result is more than the frame can
+// pay and the call runs out of gas.
+func callGas(availableGas, base uint64, callCost *uint256.Int) uint64 {
+	availableGas = availableGas - base
+	gas := availableGas - availableGas/64
+	if !callCost.IsUint64() || gas < callCost.Uint64() {
+		return gas
+	}
+	return callCost.Uint64()
+}
+
+// gasCall charges for memory, for transferring value and for creating the
+// callee when value is sent to an empty account (EIP-161), plus the gas
+// forwarded to the callee, which it leaves in evm.callGasTemp.
+func gasCall(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	var (
+		gas            uint64
+		transfersValue = !scope.Stack.Back(2).IsZero()
+		address        = BytesToAddress(scope.Stack.Back(1).Bytes())
+	)
+	if transfersValue && evm.StateDB.Empty(address) {
+		gas += callNewAccountGas
+	}
+	if transfersValue {
+		gas += callValueTransferGas
+	}
+	return addCallGas(evm, scope, memorySize, gas)
+}
+
+// gasCallCode is gasCall without the new account charge: the value stays
+// with the caller.
+func gasCallCode(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	var gas uint64
+	if !scope.Stack.Back(2).IsZero() {
+		gas += callValueTransferGas
+	}
+	return addCallGas(evm, scope, memorySize, gas)
+}
+
+func gasDelegateCall(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	return addCallGas(evm, scope, memorySize, 0)
+}
+
+func gasStaticCall(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	return addCallGas(evm, scope, memorySize, 0)
+}
+
+// addCallGas adds the memory expansion cost and the gas forwarded by a
+// call to gas.
+func addCallGas(evm *EVM, scope *ScopeContext, memorySize uint64, gas uint64) (uint64, error) {
+	memoryGas, err := memoryGasCost(scope.Memory, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	var overflow bool
+	if gas, overflow = safeAdd(gas, memoryGas); overflow {
+		return 0, ErrGasUintOverflow
+	}
+	evm.callGasTemp = callGas(scope.Contract.Gas, gas, scope.Stack.Back(0))
+	if gas, overflow = safeAdd(gas, evm.callGasTemp); overflow {
+		return 0, ErrGasUintOverflow
+	}
+	return gas, nil
+}
+
+// gasExp charges per byte of the exponent (EIP-160).
+func gasExp(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	expByteLen := uint64((scope.Stack.Back(1).BitLen() + 7) / 8)
+	return expByteLen * expByteGas, nil
+}
+
+// gasSha3 charges for memory and per word hashed.
+func gasSha3(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	gas, err := memoryGasCost(scope.Memory, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return addWordGas(gas, scope.Stack.Back(1), sha3WordGas)
+}
+
+// gasCreate2 charges for memory and for hashing the init code.
+func gasCreate2(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	gas, err := memoryGasCost(scope.Memory, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return addWordGas(gas, scope.Stack.Back(2), sha3WordGas)
+}
+
+// gasCreateEip3860 is gasCreate plus a charge per word of init code.
+func gasCreateEip3860(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	gas, err := memoryGasCost(scope.Memory, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return addWordGas(gas, scope.Stack.Back(2), initCodeWordGas)
+}
+
+// gasCreate2Eip3860 is gasCreate2 plus a charge per word of init code.
+func gasCreate2Eip3860(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	gas, err := memoryGasCost(scope.Memory, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return addWordGas(gas, scope.Stack.Back(2), sha3WordGas+initCodeWordGas)
+}
+
+// makeGasLog returns the gas function of LOGn: memory plus a charge for the
+// log itself, each topic and each byte of data.
+func makeGasLog(n uint64) gasFunc {
+	return func(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+		requestedSize, overflow := scope.Stack.Back(1).Uint64WithOverflow()
+		if overflow {
+			return 0, ErrGasUintOverflow
+		}
+		gas, err := memoryGasCost(scope.Memory, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		if gas, overflow = safeAdd(gas, logGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		if gas, overflow = safeAdd(gas, n*logTopicGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		var dataGas uint64
+		if dataGas, overflow = safeMul(requestedSize, logDataGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		if gas, overflow = safeAdd(gas, dataGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+// addWordGas adds perWord gas for every word of size to gas.
+func addWordGas(gas uint64, size *uint256.Int, perWord uint64) (uint64, error) {
+	words, overflow := size.Uint64WithOverflow()
+	if overflow {
+		return 0, ErrGasUintOverflow
+	}
+	if words, overflow = safeMul(toWordSize(words), perWord); overflow {
+		return 0, ErrGasUintOverflow
+	}
+	if gas, overflow = safeAdd(gas, words); overflow {
+		return 0, ErrGasUintOverflow
+	}
+	return gas, nil
+}
+
+// gasSLoadEIP2929 charges a cold or warm read of the slot and warms it.
+func gasSLoadEIP2929(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	addr := scope.Contract.Address
+	slot := Hash(scope.Stack.peek().Bytes32())
+	if _, slotPresent := evm.StateDB.SlotInAccessList(addr, slot); !slotPresent {
+		evm.StateDB.AddSlotToAccessList(addr, slot)
+		return coldSloadCostEIP2929, nil
+	}
+	return warmStorageReadCostEIP2929, nil
+}
+
+// makeGasSStoreFunc returns the gas function of SSTORE, which depends on
+// the original, current and new value of the slot (EIP-2200, with the
+// EIP-2929 costs), and updates the refund counter. clearingRefund is
+// refunded for clearing a slot that was set at the start of the
+// transaction.
+func makeGasSStoreFunc(clearingRefund uint64) gasFunc {
+	return func(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+		// a call with only the stipend must not be able to write storage
+		if scope.Contract.Gas <= sstoreSentryGasEIP2200 {
+			return 0, ErrOutOfGas
+		}
+		var (
+			y, x    = scope.Stack.Back(1), scope.Stack.peek()
+			addr    = scope.Contract.Address
+			slot    = Hash(x.Bytes32())
+			current = evm.StateDB.GetState(addr, slot)
+			cost    = uint64(0)
+		)
+		if _, slotPresent := evm.StateDB.SlotInAccessList(addr, slot); !slotPresent {
+			cost = coldSloadCostEIP2929
+			evm.StateDB.AddSlotToAccessList(addr, slot)
+		}
+		value := Hash(y.Bytes32())
+
+		if current == value { // noop
+			return cost + warmStorageReadCostEIP2929, nil
+		}
+		original := evm.StateDB.GetCommittedState(addr, slot)
+		if original == current {
+			if original == (Hash{}) { // create slot
+				return cost + sstoreSetGasEIP2200, nil
+			}
+			if value == (Hash{}) { // delete slot
+				evm.StateDB.AddRefund(clearingRefund)
+			}
+			// write existing slot
+			return cost + (sstoreResetGasEIP2200 - coldSloadCostEIP2929), nil
+		}
+		// the slot was already written in this transaction
+		if original != (Hash{}) {
+			if current == (Hash{}) { // recreate slot
+				evm.StateDB.SubRefund(clearingRefund)
+			} else if value == (Hash{}) { // delete slot
+				evm.StateDB.AddRefund(clearingRefund)
+			}
+		}
+		if original == value {
+			if original == (Hash{}) { // reset to original inexistent slot
+				evm.StateDB.AddRefund(sstoreSetGasEIP2200 - warmStorageReadCostEIP2929)
+			} else { // reset to original existing slot
+				evm.StateDB.AddRefund((sstoreResetGasEIP2200 - coldSloadCostEIP2929) - warmStorageReadCostEIP2929)
+			}
+		}
+		return cost + warmStorageReadCostEIP2929, nil // dirty update
+	}
+}
+
+var (
+	gasSStoreEIP2929 = makeGasSStoreFunc(sstoreClearsScheduleRefundEIP2200)
+	// EIP-3529 lowers the refund for clearing a slot
+	gasSStoreEIP3529 = makeGasSStoreFunc(sstoreClearsScheduleRefundEIP3529)
+)
+
+// gasEip2929AccountCheck charges the cold surcharge for the account on top
+// of the stack, on top of the warm cost paid as constant gas, and warms it.
+func gasEip2929AccountCheck(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	addr := BytesToAddress(scope.Stack.peek().Bytes())
+	if !evm.StateDB.AddressInAccessList(addr) {
+		evm.StateDB.AddAddressToAccessList(addr)
+		return coldAccountAccessCostEIP2929 - warmStorageReadCostEIP2929, nil
+	}
+	return 0, nil
+}
+
+// gasExtCodeCopyEIP2929 is gasExtCodeCopy plus the cold surcharge.
+func gasExtCodeCopyEIP2929(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+	gas, err := gasExtCodeCopy(evm, scope, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	coldCost, _ := gasEip2929AccountCheck(evm, scope, memorySize)
+	var overflow bool
+	if gas, overflow = safeAdd(gas, coldCost); overflow {
+		return 0, ErrGasUintOverflow
+	}
+	return gas, nil
+}
+
+// makeCallVariantGasCallEIP2929 adds the cold surcharge for the callee,
+// the second item on the stack, to the gas function of a call instruction.
+// The surcharge is paid before the forwarded gas is worked out, so that it
+// is not part of the gas left to forward.
+func makeCallVariantGasCallEIP2929(oldCalculator gasFunc) gasFunc {
+	return func(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+		addr := BytesToAddress(scope.Stack.Back(1).Bytes())
+		warmAccess := evm.StateDB.AddressInAccessList(addr)
+		coldCost := coldAccountAccessCostEIP2929 - warmStorageReadCostEIP2929
+		if !warmAccess {
+			evm.StateDB.AddAddressToAccessList(addr)
+			if !scope.Contract.UseGas(coldCost) {
+				return 0, ErrOutOfGas
+			}
+		}
+		gas, err := oldCalculator(evm, scope, memorySize)
+		if warmAccess || err != nil {
+			return gas, err
+		}
+		// the interpreter charges the surcharge again with the rest
+		scope.Contract.Gas += coldCost
+		var overflow bool
+		if gas, overflow = safeAdd(gas, coldCost); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+var (
+	gasCallEIP2929         = makeCallVariantGasCallEIP2929(gasCall)
+	gasCallCodeEIP2929     = makeCallVariantGasCallEIP2929(gasCallCode)
+	gasDelegateCallEIP2929 = makeCallVariantGasCallEIP2929(gasDelegateCall)
+	gasStaticCallEIP2929   = makeCallVariantGasCallEIP2929(gasStaticCall)
+)
+
+// makeSelfdestructGasFn returns the gas function of SELFDESTRUCT, which
+// charges for a cold beneficiary and for creating the beneficiary when
+// value is sent to an empty account. Before EIP-3529 the first
+// SELFDESTRUCT of an account is also refunded.
+func makeSelfdestructGasFn(refundsEnabled bool) gasFunc {
+	return func(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error) {
+		var (
+			gas     uint64
+			address = BytesToAddress(scope.Stack.peek().Bytes())
+		)
+		if !evm.StateDB.AddressInAccessList(address) {
+			evm.StateDB.AddAddressToAccessList(address)
+			gas = coldAccountAccessCostEIP2929
+		}
+		if evm.StateDB.Empty(address) && !evm.StateDB.GetBalance(scope.Contract.Address).IsZero() {
+			gas += createBySelfdestructGas
+		}
+		if refundsEnabled && !evm.StateDB.HasSelfDestructed(scope.Contract.Address) {
+			evm.StateDB.AddRefund(selfdestructRefundGas)
+		}
+		return gas, nil
+	}
+}
+
+var (
+	gasSelfdestructEIP2929 = makeSelfdestructGasFn(true)
+	gasSelfdestructEIP3529 = makeSelfdestructGasFn(false)
+)
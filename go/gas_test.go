@@ -0,0 +1,430 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestStaticGas(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		gas     uint64
+		used    uint64
+		success bool
+	}{
+		// PUSH1, PUSH1, ADD
+		{name: "arithmetic", code: "6001600201", gas: 100, used: 3 + 3 + 3, success: true},
+		// PUSH1, PUSH1, ADD, POP, STOP
+		{name: "stop is free", code: "600160020150" + "00", gas: 100, used: 3 + 3 + 3 + 2, success: true},
+		// JUMPDEST, PUSH1 0, JUMP: an infinite loop runs out of gas
+		{name: "infinite loop", code: "5b600056", gas: 10000, used: 10000},
+		// out of gas consumes all the gas of the frame
+		{name: "out of gas", code: "6001600201", gas: 8, used: 8},
+		// a revert returns the gas that is left
+		{name: "revert", code: "60006000fd", gas: 100, used: 3 + 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(fromHex(tt.code), tt.gas)
+			if res.Success != tt.success || res.GasUsed != tt.used {
+				t.Errorf("got success %v, gas used %d, want %v, %d (%v)", res.Success, res.GasUsed, tt.success, tt.used, res.Err)
+			}
+		})
+	}
+}
+
+func TestOutOfGasError(t *testing.T) {
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(fromHex("5b600056"), 1000)
+	var execErr *ExecutionError
+	if !errors.Is(res.Err, ErrOutOfGas) || !errors.As(res.Err, &execErr) {
+		t.Fatalf("expected ErrOutOfGas, got %v", res.Err)
+	}
+}
+
+func TestGasOpcode(t *testing.T) {
+	// PUSH1 1, GAS: GAS pushes what is left after paying for itself
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(fromHex("60015a"), 100)
+	if !res.Success || res.Stack[0].Uint64() != 100-3-2 {
+		t.Errorf("GAS pushed %v (%v), want %d", res.Stack, res.Err, 100-3-2)
+	}
+
+	res = NewEVM(BlockContext{}, TxContext{}, nil, Config{NoGasMetering: true}).Run(fromHex("5b60005a"), 0)
+	if !res.Success || res.Stack[0].Hex() != "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" || res.GasUsed != 0 {
+		t.Errorf("without metering GAS pushed %v, gas used %d", res.Stack, res.GasUsed)
+	}
+}
+
+func TestCodeDepositGas(t *testing.T) {
+	// init code returning 32 bytes of memory: 3 to expand memory by one
+	// word, 32 * 200 to store them
+	initCode := fromHex("60206000f3")
+	for _, tt := range []struct {
+		gas     uint64
+		wantErr error
+	}{
+		{gas: 3 + 3 + 3 + 6400, wantErr: nil},
+		{gas: 3 + 3 + 3 + 6399, wantErr: ErrCodeStoreOutOfGas},
+	} {
+		db := NewMemoryStateDB()
+		evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+		_, addr, leftOver, err := evm.Create(HexToAddress("0xc0"), initCode, tt.gas, new(uint256.Int))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("gas %d: got %v, want %v", tt.gas, err, tt.wantErr)
+		}
+		if err == nil && (leftOver != 0 || db.GetCodeSize(addr) != 32) {
+			t.Errorf("gas %d: %d left, code size %d", tt.gas, leftOver, db.GetCodeSize(addr))
+		}
+		if err != nil && db.Exist(addr) {
+			t.Errorf("gas %d: failed creation left an account", tt.gas)
+		}
+	}
+}
+
+func TestMemoryGasCost(t *testing.T) {
+	for _, tt := range []struct {
+		size uint64
+		want uint64
+	}{
+		{0, 0},
+		{1, 3},
+		{32, 3},
+		{33, 6},
+		{32 * 22, 22*3 + 22*22/512},
+		{32 * 1024, 1024*3 + 1024*1024/512},
+	} {
+		if got, err := memoryGasCost(NewMemory(), tt.size); err != nil || got != tt.want {
+			t.Errorf("size %d: got %d (%v), want %d", tt.size, got, err, tt.want)
+		}
+	}
+
+	// growing only pays the difference, shrinking or staying is free
+	mem := NewMemory()
+	memoryGasCost(mem, 64)
+	mem.Resize(64)
+	if got, _ := memoryGasCost(mem, 32); got != 0 {
+		t.Errorf("no growth charged %d", got)
+	}
+	if got, _ := memoryGasCost(mem, 96); got != 3 {
+		t.Errorf("one more word charged %d, want 3", got)
+	}
+	if _, err := memoryGasCost(NewMemory(), 0x1FFFFFFFE1); !errors.Is(err, ErrGasUintOverflow) {
+		t.Errorf("huge memory: got %v, want ErrGasUintOverflow", err)
+	}
+}
+
+func TestDynamicGas(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		code string
+		want uint64
+	}{
+		// PUSH1 0xff, PUSH1 2, EXP: one exponent byte
+		{"exp", "60ff600a0a", 3 + 3 + 10 + 50},
+		// PUSH2 0x0100, PUSH1 2, EXP: two exponent bytes
+		{"exp two bytes", "610100600a0a", 3 + 3 + 10 + 2*50},
+		// PUSH1 0, PUSH1 0, EXP: zero exponent is free
+		{"exp zero", "600060000a", 3 + 3 + 10},
+		// PUSH1 33, PUSH1 0, SHA3: two words hashed and expanded
+		{"sha3", "602160002000", 3 + 3 + 30 + 2*6 + 2*3},
+		// PUSH1 0, MLOAD: one word of memory
+		{"mload", "600051", 3 + 3 + 3},
+		// PUSH1 1, PUSH1 0x40, MSTORE8: three words of memory
+		{"mstore8", "6001604053", 3 + 3 + 3 + 3*3},
+		// PUSH1 64, PUSH1 0, PUSH1 0, CALLDATACOPY: two words copied
+		{"calldatacopy", "604060006000" + "37", 3 + 3 + 3 + 3 + 2*3 + 2*3},
+		// PUSH1 32, PUSH1 0, PUSH1 0, CODECOPY: one word copied
+		{"codecopy", "602060006000" + "39", 3 + 3 + 3 + 3 + 3 + 3},
+		// PUSH1 0, PUSH1 0, PUSH1 0, CODECOPY: nothing copied
+		{"codecopy empty", "600060006000" + "39", 3 + 3 + 3 + 3},
+		// PUSH1 32, PUSH1 0, RETURN: one word of memory
+		{"return", "60206000f3", 3 + 3 + 3},
+		// PUSH1 4, PUSH1 0, LOG0: four bytes of data
+		{"log0", "60046000a0", 3 + 3 + 375 + 4*8 + 3},
+		// PUSH1 0, PUSH1 0, PUSH1 0, PUSH1 0, LOG2: two topics
+		{"log2", "6000600060006000a2", 4*3 + 375 + 2*375},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewEVM(BlockContext{}, TxContext{}, NewMemoryStateDB(), Config{}).Run(fromHex(tt.code), testGas)
+			if !res.Success {
+				t.Fatalf("failed: %v", res.Err)
+			}
+			if res.GasUsed != tt.want {
+				t.Errorf("gas used %d, want %d", res.GasUsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamicGasOutOfGas(t *testing.T) {
+	// PUSH3 0x010000, MLOAD: memory expansion alone exceeds the gas
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(fromHex("62010000"+"51"), 1000)
+	if !errors.Is(res.Err, ErrOutOfGas) {
+		t.Fatalf("got %v, want ErrOutOfGas", res.Err)
+	}
+	if res.GasUsed != 1000 {
+		t.Errorf("gas used %d, want all 1000", res.GasUsed)
+	}
+}
+
+func TestAccessListGas(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		code string
+		want uint64
+	}{
+		// PUSH1 1, SLOAD, PUSH1 1, SLOAD: cold then warm
+		{"sload", "600154600154", 3 + 2100 + 3 + 100},
+		// PUSH1 1, PUSH1 1, SSTORE, PUSH1 1, SLOAD: the store warms the slot
+		{"sstore warms", "600160015560015400", 3 + 3 + 2100 + 20000 + 3 + 100},
+		// PUSH1 0xbb, BALANCE, PUSH1 0xbb, EXTCODESIZE: cold then warm
+		{"balance", "60bb3160bb3b", 3 + 2600 + 3 + 100},
+		// PUSH1 0xbb, EXTCODEHASH, twice
+		{"extcodehash", "60bb3f60bb3f", 3 + 2600 + 3 + 100},
+		// PUSH1 0, PUSH1 0, PUSH1 0, PUSH1 0xbb, EXTCODECOPY
+		{"extcodecopy", "600060006000" + "60bb3c", 4*3 + 2600},
+		// ADDRESS, BALANCE: the executing contract is not warm without Prepare
+		{"self", "3031", 2 + 2600},
+		// PUSH1 0 four times, PUSH1 0xbb, GAS, STATICCALL, twice
+		{"staticcall", "6000600060006000" + "60bb5afa" + "6000600060006000" + "60bb5afa", 2*(5*3+2) + 2600 + 100},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewEVM(BlockContext{}, TxContext{}, NewMemoryStateDB(), Config{}).Run(fromHex(tt.code), testGas)
+			if !res.Success {
+				t.Fatalf("failed: %v", res.Err)
+			}
+			if res.GasUsed != tt.want {
+				t.Errorf("gas used %d, want %d", res.GasUsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessListRevertedWithFrame(t *testing.T) {
+	// the callee reads slot 1 and reverts, so its access is undone
+	db := NewMemoryStateDB()
+	callee := HexToAddress("0xbb")
+	db.SetCode(callee, fromHex("60015460006000fd"))
+	db.Prepare(Cancun, HexToAddress("0xaa"), Address{}, &callee, nil, nil)
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	_, _, err := evm.Call(HexToAddress("0xaa"), callee, nil, 100000, new(uint256.Int))
+	if !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("got %v, want revert", err)
+	}
+	if _, slotOk := db.SlotInAccessList(callee, HexToHash("0x01")); slotOk {
+		t.Error("slot accessed by a reverted frame is still warm")
+	}
+}
+
+func TestSelfdestructGas(t *testing.T) {
+	db := NewMemoryStateDB()
+	self := HexToAddress("0xaa")
+	db.AddBalance(self, uint256.NewInt(1))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	// PUSH1 0xbb, SELFDESTRUCT: cold, empty beneficiary receiving value
+	contract := NewContract(Address{}, self, new(uint256.Int), fromHex("60bbff"), nil, testGas)
+	res := evm.RunContract(contract)
+	if want := 3 + 5000 + 2600 + 25000; !res.Success || res.GasUsed != uint64(want) {
+		t.Errorf("gas used %d, want %d (%v)", res.GasUsed, want, res.Err)
+	}
+}
+
+func TestSstoreGas(t *testing.T) {
+	// the cases of EIP-3529, which run with the slot already warm
+	for _, tt := range []struct {
+		code     string
+		original byte
+		used     uint64
+		refund   uint64
+	}{
+		{"60006000556000600055", 0, 212, 0},
+		{"60006000556001600055", 0, 20112, 0},
+		{"60016000556000600055", 0, 20112, 19900},
+		{"60016000556002600055", 0, 20112, 0},
+		{"60016000556001600055", 0, 20112, 0},
+		{"60006000556000600055", 1, 3012, 4800},
+		{"60006000556001600055", 1, 3012, 2800},
+		{"60006000556002600055", 1, 3012, 0},
+		{"60026000556000600055", 1, 3012, 4800},
+		{"60026000556003600055", 1, 3012, 0},
+		{"60026000556001600055", 1, 3012, 2800},
+		{"60026000556002600055", 1, 3012, 0},
+		{"60016000556000600055", 1, 3012, 4800},
+		{"60016000556002600055", 1, 3012, 0},
+		{"60016000556001600055", 1, 212, 0},
+		{"600160005560006000556001600055", 0, 40118, 19900},
+		{"600060005560016000556000600055", 1, 5918, 7600},
+	} {
+		var (
+			db   = NewMemoryStateDB()
+			addr = HexToAddress("0xaa")
+			slot = Hash{}
+		)
+		db.SetState(addr, slot, BytesToHash([]byte{tt.original}))
+		db.Finalise()
+		db.AddSlotToAccessList(addr, slot)
+
+		evm := NewEVM(BlockContext{}, TxContext{}, db, Config{Fork: London})
+		res := evm.RunContract(NewContract(Address{}, addr, new(uint256.Int), fromHex(tt.code), nil, testGas))
+		if !res.Success {
+			t.Fatalf("%s: %v", tt.code, res.Err)
+		}
+		if res.GasUsed != tt.used || db.GetRefund() != tt.refund {
+			t.Errorf("%s (original %d): used %d, refund %d, want %d, %d", tt.code, tt.original, res.GasUsed, db.GetRefund(), tt.used, tt.refund)
+		}
+		if want := min64(tt.refund, tt.used/5); res.Refund != want {
+			t.Errorf("%s: result refund %d, want %d", tt.code, res.Refund, want)
+		}
+	}
+}
+
+func TestSstoreRefundBerlin(t *testing.T) {
+	db := NewMemoryStateDB()
+	addr := HexToAddress("0xaa")
+	db.SetState(addr, Hash{}, HexToHash("0x01"))
+	db.Finalise()
+
+	// PUSH1 0, PUSH1 0, SSTORE: clearing a cold slot
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{Fork: Berlin})
+	res := evm.RunContract(NewContract(Address{}, addr, new(uint256.Int), fromHex("6000600055"), nil, testGas))
+	if want := uint64(3 + 3 + 2100 + 2900); !res.Success || res.GasUsed != want {
+		t.Fatalf("used %d, want %d (%v)", res.GasUsed, want, res.Err)
+	}
+	if db.GetRefund() != 15000 || res.Refund != res.GasUsed/2 {
+		t.Errorf("refund counter %d, result refund %d", db.GetRefund(), res.Refund)
+	}
+}
+
+func TestSstoreSentry(t *testing.T) {
+	for _, tt := range []struct {
+		left    uint64
+		success bool
+	}{
+		{left: 2300, success: false},
+		{left: 2301, success: true},
+	} {
+		db := NewMemoryStateDB()
+		addr := HexToAddress("0xaa")
+		db.AddSlotToAccessList(addr, Hash{})
+		// PUSH1 0, PUSH1 0, SSTORE: a no-op costing 100, blocked by the
+		// sentry when only the stipend is left
+		evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+		res := evm.RunContract(NewContract(Address{}, addr, new(uint256.Int), fromHex("6000600055"), nil, 6+tt.left))
+		if res.Success != tt.success {
+			t.Errorf("%d left: success %v, want %v (%v)", tt.left, res.Success, tt.success, res.Err)
+		}
+	}
+}
+
+func TestRefundRevertedWithFrame(t *testing.T) {
+	db := NewMemoryStateDB()
+	callee := HexToAddress("0xbb")
+	// PUSH1 0, PUSH1 0, SSTORE, PUSH1 0, PUSH1 0, REVERT
+	db.SetCode(callee, fromHex("600060005560006000fd"))
+	db.SetState(callee, Hash{}, HexToHash("0x01"))
+	db.Finalise()
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	_, _, err := evm.Call(HexToAddress("0xaa"), callee, nil, 100000, new(uint256.Int))
+	if !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("got %v, want revert", err)
+	}
+	if db.GetRefund() != 0 {
+		t.Errorf("refund of a reverted frame kept: %d", db.GetRefund())
+	}
+}
+
+func TestCallGasForwarding(t *testing.T) {
+	db := NewMemoryStateDB()
+	callee := HexToAddress("0xbb")
+	// GAS, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, RETURN: returns the gas it got
+	db.SetCode(callee, fromHex("5a60005260206000f3"))
+	// CALL 0xbb with all the gas, returning the output
+	code := fromHex("60206000600060006000" + "60bb5af1" + "60206000f3")
+	res := NewEVM(BlockContext{}, TxContext{}, db, Config{}).Run(code, 100000)
+	if !res.Success {
+		t.Fatal(res.Err)
+	}
+	// 6 pushes and GAS, CALL to a cold account, one word of memory
+	left := uint64(100000 - 6*3 - 2 - 100 - 2500 - 3)
+	if got, want := new(uint256.Int).SetBytes(res.ReturnData).Uint64(), left-left/64-2; got != want {
+		t.Errorf("callee had %d gas, want %d", got, want)
+	}
+}
+
+func TestCallStipend(t *testing.T) {
+	db := NewMemoryStateDB()
+	caller, callee := HexToAddress("0xaa"), HexToAddress("0xbb")
+	db.AddBalance(caller, uint256.NewInt(10))
+	db.SetCode(callee, fromHex("5a60005260206000f3"))
+
+	// CALL 0xbb with value 1 and no gas: the callee runs on the stipend
+	code := fromHex("6020600060006000" + "6001" + "60bb6000f1" + "60206000f3")
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	res := evm.RunContract(NewContract(Address{}, caller, new(uint256.Int), code, nil, 100000))
+	if !res.Success {
+		t.Fatal(res.Err)
+	}
+	if got := new(uint256.Int).SetBytes(res.ReturnData).Uint64(); got != callStipend-2 {
+		t.Errorf("callee had %d gas, want %d", got, callStipend-2)
+	}
+}
+
+func TestCallNewAccountGas(t *testing.T) {
+	db := NewMemoryStateDB()
+	caller := HexToAddress("0xaa")
+	db.AddBalance(caller, uint256.NewInt(10))
+
+	// CALL the empty account 0xcc with value 1 and no gas: the unused
+	// stipend comes back to the caller
+	code := fromHex("6000600060006000" + "6001" + "60cc6000f1")
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	res := evm.RunContract(NewContract(Address{}, caller, new(uint256.Int), code, nil, 100000))
+	if !res.Success {
+		t.Fatal(res.Err)
+	}
+	if want := uint64(7*3 + 100 + 2500 + 9000 + 25000 - 2300); res.GasUsed != want {
+		t.Errorf("gas used %d, want %d", res.GasUsed, want)
+	}
+}
+
+func TestCreateGasForwarding(t *testing.T) {
+	// store the init code JUMPDEST, PUSH1 0, JUMP, which loops until it
+	// runs out of gas, then CREATE and GAS: the caller keeps one 64th
+	code := fromHex("635b600056600052" + "6004601c6000f0" + "5a")
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).Run(code, 100000)
+	if !res.Success {
+		t.Fatal(res.Err)
+	}
+	// PUSH4, PUSH1, MSTORE with one word of memory, 3 pushes, CREATE with
+	// one word of init code
+	left := uint64(100000 - 3 - 3 - 3 - 3 - 3*3 - 32000 - 2)
+	if got, want := res.Stack[0].Uint64(), left/64-2; got != want {
+		t.Errorf("GAS after CREATE = %d, want %d", got, want)
+	}
+	if !res.Stack[1].IsZero() {
+		t.Errorf("failed creation pushed %v", res.Stack[1])
+	}
+}
+
+func TestRefundPerRun(t *testing.T) {
+	db := NewMemoryStateDB()
+	addr := HexToAddress("0xaa")
+	db.SetState(addr, Hash{}, HexToHash("0x01"))
+	db.Finalise()
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+
+	// PUSH1 0, PUSH1 0, SSTORE: clears the slot and earns a refund
+	res := evm.RunContract(NewContract(Address{}, addr, nil, fromHex("6000600055"), nil, testGas))
+	if !res.Success || res.Refund != res.GasUsed/5 {
+		t.Fatalf("first run: refund %d, gas used %d (%v)", res.Refund, res.GasUsed, res.Err)
+	}
+	// PUSH1 1, SLOAD, POP on the same state earns nothing
+	res = evm.RunContract(NewContract(Address{}, addr, nil, fromHex("6001545000"), nil, testGas))
+	if !res.Success || res.Refund != 0 {
+		t.Errorf("second run: refund %d, want 0 (%v)", res.Refund, res.Err)
+	}
+}
@@ -2,9 +2,9 @@ module evm-from-scratch-go
 
 go 1.18
 
-require github.com/holiman/uint256 v1.2.1
-
 require (
-	golang.org/x/crypto v0.3.0 // indirect
-	golang.org/x/sys v0.2.0 // indirect
+	github.com/holiman/uint256 v1.2.1
+	golang.org/x/crypto v0.3.0
 )
+
+require golang.org/x/sys v0.2.0 // indirect
@@ -0,0 +1,837 @@
+package evm
+
+import (
+	"errors"
+
+	"github.com/holiman/uint256"
+)
+
+func opAdd(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Add(&x, y)
+	return nil, nil
+}
+
+func opSub(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Sub(&x, y)
+	return nil, nil
+}
+
+func opMul(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Mul(&x, y)
+	return nil, nil
+}
+
+func opDiv(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Div(&x, y)
+	return nil, nil
+}
+
+func opSdiv(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.SDiv(&x, y)
+	return nil, nil
+}
+
+func opMod(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Mod(&x, y)
+	return nil, nil
+}
+
+func opSmod(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.SMod(&x, y)
+	return nil, nil
+}
+
+func opExp(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	base, exponent := scope.Stack.pop(), scope.Stack.peek()
+	exponent.Exp(&base, exponent)
+	return nil, nil
+}
+
+func opSignExtend(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	back, num := scope.Stack.pop(), scope.Stack.peek()
+	num.ExtendSign(num, &back)
+	return nil, nil
+}
+
+func opNot(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x := scope.Stack.peek()
+	x.Not(x)
+	return nil, nil
+}
+
+func opLt(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	setBool(y, x.Lt(y))
+	return nil, nil
+}
+
+func opGt(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	setBool(y, x.Gt(y))
+	return nil, nil
+}
+
+func opSlt(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	setBool(y, x.Slt(y))
+	return nil, nil
+}
+
+func opSgt(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	setBool(y, x.Sgt(y))
+	return nil, nil
+}
+
+func opEq(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	setBool(y, x.Eq(y))
+	return nil, nil
+}
+
+func opIszero(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x := scope.Stack.peek()
+	setBool(x, x.IsZero())
+	return nil, nil
+}
+
+func opAnd(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.And(&x, y)
+	return nil, nil
+}
+
+func opOr(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Or(&x, y)
+	return nil, nil
+}
+
+func opXor(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y := scope.Stack.pop(), scope.Stack.peek()
+	y.Xor(&x, y)
+	return nil, nil
+}
+
+func opByte(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	th, val := scope.Stack.pop(), scope.Stack.peek()
+	val.Byte(&th)
+	return nil, nil
+}
+
+func opAddmod(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y, z := scope.Stack.pop(), scope.Stack.pop(), scope.Stack.peek()
+	z.AddMod(&x, &y, z)
+	return nil, nil
+}
+
+func opMulmod(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x, y, z := scope.Stack.pop(), scope.Stack.pop(), scope.Stack.peek()
+	z.MulMod(&x, &y, z)
+	return nil, nil
+}
+
+// opSHL shifts value left by shift bits. Shifts of 256 or more give 0.
+func opSHL(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	shift, value := scope.Stack.pop(), scope.Stack.peek()
+	if shift.LtUint64(256) {
+		value.Lsh(value, uint(shift.Uint64()))
+	} else {
+		value.Clear()
+	}
+	return nil, nil
+}
+
+// opSHR shifts value right by shift bits, filling with zeros.
+func opSHR(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	shift, value := scope.Stack.pop(), scope.Stack.peek()
+	if shift.LtUint64(256) {
+		value.Rsh(value, uint(shift.Uint64()))
+	} else {
+		value.Clear()
+	}
+	return nil, nil
+}
+
+// opSAR shifts value right by shift bits, filling with the sign bit.
+func opSAR(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	shift, value := scope.Stack.pop(), scope.Stack.peek()
+	if shift.GtUint64(255) {
+		if value.Sign() >= 0 {
+			value.Clear()
+		} else {
+			value.SetAllOne()
+		}
+		return nil, nil
+	}
+	value.SRsh(value, uint(shift.Uint64()))
+	return nil, nil
+}
+
+func opSha3(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	offset, size := scope.Stack.pop(), scope.Stack.peek()
+	size.Set(scope.Memory.Sha3(offset.Uint64(), size.Uint64()))
+	return nil, nil
+}
+
+func opAddress(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetBytes(scope.Contract.Address.Bytes()))
+	return nil, nil
+}
+
+func opCaller(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetBytes(scope.Contract.CallerAddress.Bytes()))
+	return nil, nil
+}
+
+func opBalance(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	slot := scope.Stack.peek()
+	address := BytesToAddress(slot.Bytes())
+	slot.Set(evm.StateDB.GetBalance(address))
+	return nil, nil
+}
+
+func opOrigin(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetBytes(evm.Origin.Bytes()))
+	return nil, nil
+}
+
+func opGasprice(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(intOrZero(evm.GasPrice))
+	return nil, nil
+}
+
+func opCallValue(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(intOrZero(scope.Contract.Value))
+	return nil, nil
+}
+
+func opCallDataLoad(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	x := scope.Stack.peek()
+	if offset, overflow := x.Uint64WithOverflow(); !overflow {
+		x.SetBytes(getData(scope.Contract.Input, offset, 32))
+	} else {
+		x.Clear()
+	}
+	return nil, nil
+}
+
+func opCallDataSize(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(uint64(len(scope.Contract.Input))))
+	return nil, nil
+}
+
+func opCallDataCopy(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		memOffset  = scope.Stack.pop()
+		dataOffset = scope.Stack.pop()
+		length     = scope.Stack.pop()
+	)
+	// an offset past the end of the input reads only zeros
+	dataOffset64, overflow := dataOffset.Uint64WithOverflow()
+	if overflow {
+		dataOffset64 = maxUint64
+	}
+	// these are safe to truncate: the memory size function has checked them
+	memOffset64, length64 := memOffset.Uint64(), length.Uint64()
+	scope.Memory.Set(memOffset64, length64, getData(scope.Contract.Input, dataOffset64, length64))
+	return nil, nil
+}
+
+func opCodeSize(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(uint64(len(scope.Contract.Code))))
+	return nil, nil
+}
+
+func opCodeCopy(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		memOffset  = scope.Stack.pop()
+		codeOffset = scope.Stack.pop()
+		length     = scope.Stack.pop()
+	)
+	codeOffset64, overflow := codeOffset.Uint64WithOverflow()
+	if overflow {
+		codeOffset64 = maxUint64
+	}
+	length64 := length.Uint64()
+	scope.Memory.Set(memOffset.Uint64(), length64, getData(scope.Contract.Code, codeOffset64, length64))
+	return nil, nil
+}
+
+func opExtCodeSize(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	slot := scope.Stack.peek()
+	slot.SetUint64(uint64(evm.StateDB.GetCodeSize(BytesToAddress(slot.Bytes()))))
+	return nil, nil
+}
+
+func opExtCodeCopy(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		a          = scope.Stack.pop()
+		memOffset  = scope.Stack.pop()
+		codeOffset = scope.Stack.pop()
+		length     = scope.Stack.pop()
+	)
+	codeOffset64, overflow := codeOffset.Uint64WithOverflow()
+	if overflow {
+		codeOffset64 = maxUint64
+	}
+	length64 := length.Uint64()
+	code := evm.StateDB.GetCode(BytesToAddress(a.Bytes()))
+	scope.Memory.Set(memOffset.Uint64(), length64, getData(code, codeOffset64, length64))
+	return nil, nil
+}
+
+// opExtCodeHash pushes the code hash of an account (EIP-1052). Empty and
+// non-existent accounts hash to zero; accounts without code otherwise hash
+// to the keccak256 of empty code.
+func opExtCodeHash(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	slot := scope.Stack.peek()
+	address := BytesToAddress(slot.Bytes())
+	if evm.StateDB.Empty(address) {
+		slot.Clear()
+	} else {
+		codeHash := evm.StateDB.GetCodeHash(address)
+		slot.SetBytes(codeHash[:])
+	}
+	return nil, nil
+}
+
+func opSelfBalance(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(evm.StateDB.GetBalance(scope.Contract.Address))
+	return nil, nil
+}
+
+func opReturnDataSize(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(uint64(len(evm.returnData))))
+	return nil, nil
+}
+
+// opReturnDataCopy copies return data to memory. Unlike the other copy
+// instructions, reading past the end of the data is an error (EIP-211).
+func opReturnDataCopy(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		memOffset  = scope.Stack.pop()
+		dataOffset = scope.Stack.pop()
+		length     = scope.Stack.pop()
+	)
+	offset64, overflow := dataOffset.Uint64WithOverflow()
+	if overflow {
+		return nil, ErrReturnDataOutOfBounds
+	}
+	end := new(uint256.Int).Add(&dataOffset, &length)
+	end64, overflow := end.Uint64WithOverflow()
+	if overflow || uint64(len(evm.returnData)) < end64 {
+		return nil, ErrReturnDataOutOfBounds
+	}
+	scope.Memory.Set(memOffset.Uint64(), length.Uint64(), evm.returnData[offset64:end64])
+	return nil, nil
+}
+
+// opBlobHash pushes the versioned hash of the blob at the given index of
+// the transaction (EIP-4844), or zero if there is none.
+func opBlobHash(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	index := scope.Stack.peek()
+	if index.LtUint64(uint64(len(evm.BlobHashes))) {
+		blobHash := evm.BlobHashes[index.Uint64()]
+		index.SetBytes(blobHash[:])
+	} else {
+		index.Clear()
+	}
+	return nil, nil
+}
+
+func opBlockhash(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	num := scope.Stack.peek()
+	num64, overflow := num.Uint64WithOverflow()
+	if overflow {
+		num.Clear()
+		return nil, nil
+	}
+	// only the 256 most recent blocks are available, excluding the current one
+	var lower, upper uint64
+	upper = evm.Context.Number
+	if upper < 257 {
+		lower = 0
+	} else {
+		lower = upper - 256
+	}
+	if num64 >= lower && num64 < upper && evm.Context.GetHash != nil {
+		h := evm.Context.GetHash(num64)
+		num.SetBytes(h[:])
+	} else {
+		num.Clear()
+	}
+	return nil, nil
+}
+
+func opCoinbase(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetBytes(evm.Context.Coinbase.Bytes()))
+	return nil, nil
+}
+
+func opTimestamp(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(evm.Context.Time))
+	return nil, nil
+}
+
+func opNumber(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(evm.Context.Number))
+	return nil, nil
+}
+
+// opDifficulty pushes the block difficulty, which is PREVRANDAO (EIP-4399)
+// from Paris on.
+func opDifficulty(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(intOrZero(evm.Context.Difficulty))
+	return nil, nil
+}
+
+func opGasLimit(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(evm.Context.GasLimit))
+	return nil, nil
+}
+
+func opChainID(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int).SetUint64(evm.Config.ChainID))
+	return nil, nil
+}
+
+func opBaseFee(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(intOrZero(evm.Context.BaseFee))
+	return nil, nil
+}
+
+func opBlobBaseFee(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(intOrZero(evm.Context.BlobBaseFee))
+	return nil, nil
+}
+
+func opPop(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.pop()
+	return nil, nil
+}
+
+func opMload(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	v := scope.Stack.peek()
+	v.Set(scope.Memory.Get(v.Uint64()))
+	return nil, nil
+}
+
+func opMstore(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	mStart, val := scope.Stack.pop(), scope.Stack.pop()
+	scope.Memory.Put(mStart.Uint64(), &val)
+	return nil, nil
+}
+
+func opMstore8(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	off, val := scope.Stack.pop(), scope.Stack.pop()
+	scope.Memory.PutByte(off.Uint64(), byte(val.Uint64()))
+	return nil, nil
+}
+
+// opMcopy copies memory to memory (EIP-5656).
+func opMcopy(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		dst    = scope.Stack.pop()
+		src    = scope.Stack.pop()
+		length = scope.Stack.pop()
+	)
+	// these are safe to truncate: the memory size function has checked them
+	scope.Memory.Copy(dst.Uint64(), src.Uint64(), length.Uint64())
+	return nil, nil
+}
+
+func opSload(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	loc := scope.Stack.peek()
+	value := evm.StateDB.GetState(scope.Contract.Address, loc.Bytes32())
+	loc.SetBytes(value[:])
+	return nil, nil
+}
+
+func opSstore(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		loc = scope.Stack.pop()
+		val = scope.Stack.pop()
+	)
+	evm.StateDB.SetState(scope.Contract.Address, loc.Bytes32(), val.Bytes32())
+	return nil, nil
+}
+
+// opTload implements TLOAD (EIP-1153).
+func opTload(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	loc := scope.Stack.peek()
+	value := evm.StateDB.GetTransientState(scope.Contract.Address, loc.Bytes32())
+	loc.SetBytes(value[:])
+	return nil, nil
+}
+
+// opTstore implements TSTORE (EIP-1153).
+func opTstore(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		loc = scope.Stack.pop()
+		val = scope.Stack.pop()
+	)
+	evm.StateDB.SetTransientState(scope.Contract.Address, loc.Bytes32(), val.Bytes32())
+	return nil, nil
+}
+
+func opJump(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	pos := scope.Stack.pop()
+	if !scope.Contract.validJumpdest(&pos) {
+		return nil, ErrInvalidJump
+	}
+	*pc = pos.Uint64()
+	return nil, nil
+}
+
+func opJumpi(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	pos, cond := scope.Stack.pop(), scope.Stack.pop()
+	if cond.IsZero() {
+		*pc++
+		return nil, nil
+	}
+	if !scope.Contract.validJumpdest(&pos) {
+		return nil, ErrInvalidJump
+	}
+	*pc = pos.Uint64()
+	return nil, nil
+}
+
+func opJumpdest(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	return nil, nil
+}
+
+func opPc(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(uint256.NewInt(*pc))
+	return nil, nil
+}
+
+func opMsize(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(uint256.NewInt(scope.Memory.Len()))
+	return nil, nil
+}
+
+func opGas(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	if evm.Config.NoGasMetering {
+		scope.Stack.push(new(uint256.Int).SetAllOne())
+	} else {
+		scope.Stack.push(new(uint256.Int).SetUint64(scope.Contract.Gas))
+	}
+	return nil, nil
+}
+
+func opReturn(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		offset = scope.Stack.pop()
+		size   = scope.Stack.pop()
+	)
+	return scope.Memory.GetCopy(offset.Uint64(), size.Uint64()), nil
+}
+
+// opRevert halts like opReturn, but fails the frame so that its state
+// changes are undone.
+func opRevert(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		offset = scope.Stack.pop()
+		size   = scope.Stack.pop()
+	)
+	return scope.Memory.GetCopy(offset.Uint64(), size.Uint64()), ErrExecutionReverted
+}
+
+func opCreate(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		value  = scope.Stack.pop()
+		offset = scope.Stack.pop()
+		size   = scope.Stack.pop()
+	)
+	if evm.Config.Fork >= Shanghai && size.Uint64() > MaxInitCodeSize {
+		return nil, ErrMaxInitCodeSizeExceeded
+	}
+	input := scope.Memory.GetCopy(offset.Uint64(), size.Uint64())
+	// the new contract gets all but one 64th of the remaining gas (EIP-150)
+	gas := scope.Contract.Gas
+	gas -= gas / 64
+	scope.Contract.UseGas(gas)
+
+	res, addr, returnGas, err := evm.Create(scope.Contract.Address, input, gas, &value)
+	scope.Contract.Gas += returnGas
+	pushCreateResult(evm, scope, res, addr, err)
+	return nil, nil
+}
+
+func opCreate2(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		value  = scope.Stack.pop()
+		offset = scope.Stack.pop()
+		size   = scope.Stack.pop()
+		salt   = scope.Stack.pop()
+	)
+	if evm.Config.Fork >= Shanghai && size.Uint64() > MaxInitCodeSize {
+		return nil, ErrMaxInitCodeSizeExceeded
+	}
+	input := scope.Memory.GetCopy(offset.Uint64(), size.Uint64())
+	gas := scope.Contract.Gas
+	gas -= gas / 64
+	scope.Contract.UseGas(gas)
+
+	res, addr, returnGas, err := evm.Create2(scope.Contract.Address, input, gas, &value, &salt)
+	scope.Contract.Gas += returnGas
+	pushCreateResult(evm, scope, res, addr, err)
+	return nil, nil
+}
+
+// pushCreateResult pushes the address of the new contract, or zero if the
+// creation failed. Only a reverting init code leaves return data behind.
+func pushCreateResult(evm *EVM, scope *ScopeContext, res []byte, addr Address, err error) {
+	stackvalue := new(uint256.Int)
+	if err == nil {
+		stackvalue.SetBytes(addr.Bytes())
+	}
+	scope.Stack.push(stackvalue)
+	if errors.Is(err, ErrExecutionReverted) {
+		evm.returnData = res
+	} else {
+		evm.returnData = nil
+	}
+}
+
+func opCall(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	stack := scope.Stack
+	gas := takeCallGas(evm, scope, stack.pop())
+	addr, value, inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	// a CALL without value is allowed in a read-only frame
+	if evm.readOnly && !value.IsZero() {
+		return nil, ErrWriteProtection
+	}
+	toAddr := BytesToAddress(addr.Bytes())
+	args := scope.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+	if !value.IsZero() {
+		gas += callStipend
+	}
+
+	ret, returnGas, err := evm.Call(scope.Contract.Address, toAddr, args, gas, &value)
+	scope.Contract.Gas += returnGas
+	return pushCallResult(evm, scope, ret, err, &retOffset, &retSize)
+}
+
+func opCallCode(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	stack := scope.Stack
+	gas := takeCallGas(evm, scope, stack.pop())
+	addr, value, inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	toAddr := BytesToAddress(addr.Bytes())
+	args := scope.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+	if !value.IsZero() {
+		gas += callStipend
+	}
+
+	ret, returnGas, err := evm.CallCode(scope.Contract.Address, toAddr, args, gas, &value)
+	scope.Contract.Gas += returnGas
+	return pushCallResult(evm, scope, ret, err, &retOffset, &retSize)
+}
+
+func opDelegateCall(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	stack := scope.Stack
+	gas := takeCallGas(evm, scope, stack.pop())
+	addr, inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	toAddr := BytesToAddress(addr.Bytes())
+	args := scope.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+
+	ret, returnGas, err := evm.DelegateCall(scope.Contract.CallerAddress, scope.Contract.Address, toAddr, args, gas, scope.Contract.Value)
+	scope.Contract.Gas += returnGas
+	return pushCallResult(evm, scope, ret, err, &retOffset, &retSize)
+}
+
+func opStaticCall(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	stack := scope.Stack
+	gas := takeCallGas(evm, scope, stack.pop())
+	addr, inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	toAddr := BytesToAddress(addr.Bytes())
+	args := scope.Memory.GetCopy(inOffset.Uint64(), inSize.Uint64())
+
+	ret, returnGas, err := evm.StaticCall(scope.Contract.Address, toAddr, args, gas)
+	scope.Contract.Gas += returnGas
+	return pushCallResult(evm, scope, ret, err, &retOffset, &retSize)
+}
+
+// takeCallGas returns the gas a call forwards. Its gas function has worked
+// it out and charged it to the calling frame; without gas metering the
+// requested amount is taken, capped at the gas the frame has left.
+func takeCallGas(evm *EVM, scope *ScopeContext, requested uint256.Int) uint64 {
+	if !evm.Config.NoGasMetering {
+		return evm.callGasTemp
+	}
+	gas := scope.Contract.Gas
+	if requested.IsUint64() && requested.Uint64() < gas {
+		gas = requested.Uint64()
+	}
+	scope.Contract.Gas -= gas
+	return gas
+}
+
+// pushCallResult pushes the success flag of a call and copies its output to
+// memory, truncated to retSize bytes. The output of a revert is copied too.
+// A failing call does not fail the calling frame.
+func pushCallResult(evm *EVM, scope *ScopeContext, ret []byte, err error, retOffset, retSize *uint256.Int) ([]byte, error) {
+	success := new(uint256.Int)
+	if err == nil {
+		success.SetOne()
+	}
+	scope.Stack.push(success)
+	if err == nil || errors.Is(err, ErrExecutionReverted) {
+		scope.Memory.Set(retOffset.Uint64(), min64(retSize.Uint64(), uint64(len(ret))), ret)
+	}
+	evm.returnData = ret
+	return nil, nil
+}
+
+// opSelfdestruct sends the whole balance to the beneficiary and destroys
+// the account at the end of the transaction. If the account is its own
+// beneficiary, the balance is burnt.
+func opSelfdestruct(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	var (
+		beneficiary = scope.Stack.pop()
+		self        = scope.Contract.Address
+		balance     = evm.StateDB.GetBalance(self)
+	)
+	evm.StateDB.AddBalance(BytesToAddress(beneficiary.Bytes()), balance)
+	evm.StateDB.SelfDestruct(self)
+	if evm.Config.EagerSelfDestruct {
+		evm.StateDB.SetCode(self, nil)
+		evm.StateDB.SetNonce(self, 0)
+	}
+	return nil, nil
+}
+
+// opSelfdestruct6780 sends the whole balance to the beneficiary, but only
+// destroys accounts created in the same transaction (EIP-6780). An account
+// that is its own beneficiary keeps its balance, unless it is destroyed.
+func opSelfdestruct6780(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	if evm.Config.EagerSelfDestruct {
+		return opSelfdestruct(pc, evm, scope)
+	}
+	var (
+		beneficiary = scope.Stack.pop()
+		self        = scope.Contract.Address
+		balance     = evm.StateDB.GetBalance(self)
+	)
+	evm.StateDB.SubBalance(self, balance)
+	evm.StateDB.AddBalance(BytesToAddress(beneficiary.Bytes()), balance)
+	evm.StateDB.SelfDestruct6780(self)
+	return nil, nil
+}
+
+func opStop(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	return nil, nil
+}
+
+func opInvalid(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	return nil, &ErrInvalidOpCode{OpCode: INVALID}
+}
+
+func opPush0(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+	scope.Stack.push(new(uint256.Int))
+	return nil, nil
+}
+
+// makePush returns the PUSHn instruction for n = size. Push data running
+// past the end of the code is zero-padded.
+func makePush(size uint64) executionFunc {
+	return func(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+		var (
+			codeLen = uint64(len(scope.Contract.Code))
+			start   = min64(codeLen, *pc+1)
+			end     = min64(codeLen, start+size)
+			data    [32]byte
+		)
+		copy(data[:size], scope.Contract.Code[start:end])
+		scope.Stack.push(new(uint256.Int).SetBytes(data[:size]))
+		*pc += size
+		return nil, nil
+	}
+}
+
+// makeLog returns the LOGn instruction for n = size topics.
+func makeLog(size int) executionFunc {
+	return func(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+		var (
+			mStart = scope.Stack.pop()
+			mSize  = scope.Stack.pop()
+			topics = make([]Hash, size)
+		)
+		for i := 0; i < size; i++ {
+			addr := scope.Stack.pop()
+			topics[i] = addr.Bytes32()
+		}
+		evm.StateDB.AddLog(&Log{
+			Address: scope.Contract.Address,
+			Topics:  topics,
+			Data:    scope.Memory.GetCopy(mStart.Uint64(), mSize.Uint64()),
+			PC:      *pc,
+			Depth:   evm.depth,
+		})
+		return nil, nil
+	}
+}
+
+// makeDup returns the DUPn instruction.
+func makeDup(n int) executionFunc {
+	return func(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+		scope.Stack.dup(n)
+		return nil, nil
+	}
+}
+
+// makeSwap returns the SWAPn instruction.
+func makeSwap(n int) executionFunc {
+	return func(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error) {
+		scope.Stack.swap(n)
+		return nil, nil
+	}
+}
+
+// setBool sets x to 1 if b holds and to 0 otherwise.
+func setBool(x *uint256.Int, b bool) {
+	if b {
+		x.SetOne()
+	} else {
+		x.Clear()
+	}
+}
+
+// getData returns size bytes of data starting at start. Bytes past the end
+// of data read as zero.
+func getData(data []byte, start, size uint64) []byte {
+	length := uint64(len(data))
+	if start > length {
+		start = length
+	}
+	end := start + size
+	if end > length || end < start {
+		end = length
+	}
+	padded := make([]byte, size)
+	copy(padded, data[start:end])
+	return padded
+}
+
+// intOrZero returns a copy of x, treating nil as zero.
+func intOrZero(x *uint256.Int) *uint256.Int {
+	if x == nil {
+		return new(uint256.Int)
+	}
+	return new(uint256.Int).Set(x)
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
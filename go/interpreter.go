@@ -0,0 +1,115 @@
+package evm
+
+import (
+	"math/bits"
+)
+
+// ScopeContext holds the state of the frame an instruction runs in.
+type ScopeContext struct {
+	Memory   *Memory
+	Stack    *Stack
+	Contract *Contract
+}
+
+// run executes the code of scope until it halts or fails, dispatching
+// every instruction through the jump table of the active fork.
+func (evm *EVM) run(scope *ScopeContext) (ret []byte, err error) {
+	evm.depth++
+	defer func() { evm.depth-- }()
+
+	// the return data of calls made by an outer frame is not visible here
+	evm.returnData = nil
+
+	var (
+		pc    = uint64(0)
+		stack = scope.Stack
+		mem   = scope.Memory
+	)
+	for {
+		op := scope.Contract.GetOp(pc)
+		operation := evm.table[op]
+		if operation == nil {
+			return nil, &ExecutionError{PC: pc, Op: op, Err: &ErrInvalidOpCode{OpCode: op}}
+		}
+		if sLen := stack.Len(); sLen < operation.minStack {
+			return nil, &ExecutionError{PC: pc, Op: op, Err: &ErrStackUnderflow{StackLen: sLen, Required: operation.minStack}}
+		} else if sLen > operation.maxStack {
+			return nil, &ExecutionError{PC: pc, Op: op, Err: &ErrStackOverflow{StackLen: sLen, Limit: operation.maxStack}}
+		}
+
+		if !evm.Config.NoGasMetering && !scope.Contract.UseGas(operation.constantGas) {
+			return nil, &ExecutionError{PC: pc, Op: op, Err: ErrOutOfGas}
+		}
+		if evm.readOnly && operation.writes {
+			return nil, &ExecutionError{PC: pc, Op: op, Err: ErrWriteProtection}
+		}
+
+		var memorySize uint64
+		if operation.memorySize != nil {
+			memSize, overflow := operation.memorySize(stack)
+			if overflow {
+				return nil, &ExecutionError{PC: pc, Op: op, Err: ErrGasUintOverflow}
+			}
+			// memory is expanded in whole words
+			if memSize, overflow = safeMul(toWordSize(memSize), 32); overflow {
+				return nil, &ExecutionError{PC: pc, Op: op, Err: ErrGasUintOverflow}
+			}
+			if memSize > evm.Config.MaxMemory {
+				return nil, &ExecutionError{PC: pc, Op: op, Err: ErrMemoryLimit}
+			}
+			memorySize = memSize
+		}
+		// dynamic gas, including memory expansion, is paid before memory
+		// is touched
+		if operation.dynamicGas != nil && !evm.Config.NoGasMetering {
+			dynamicCost, err := operation.dynamicGas(evm, scope, memorySize)
+			if err != nil {
+				return nil, &ExecutionError{PC: pc, Op: op, Err: err}
+			}
+			if !scope.Contract.UseGas(dynamicCost) {
+				return nil, &ExecutionError{PC: pc, Op: op, Err: ErrOutOfGas}
+			}
+		}
+		if memorySize > 0 {
+			mem.Resize(memorySize)
+		}
+
+		opPC := pc
+		ret, err = operation.execute(&pc, evm, scope)
+		if err == ErrExecutionReverted {
+			// REVERT keeps its output
+			return ret, &ExecutionError{PC: opPC, Op: op, Err: err}
+		}
+		if err != nil {
+			return nil, &ExecutionError{PC: opPC, Op: op, Err: err}
+		}
+		if operation.halts {
+			return ret, nil
+		}
+		if !operation.jumps {
+			pc++
+		}
+	}
+}
+
+// toWordSize returns the number of 32-byte words needed to hold size bytes.
+func toWordSize(size uint64) uint64 {
+	if size > maxUint64-31 {
+		return maxUint64/32 + 1
+	}
+	return (size + 31) / 32
+}
+
+const maxUint64 = 1<<64 - 1
+
+// safeAdd returns x+y and whether the addition overflowed.
+func safeAdd(x, y uint64) (uint64, bool) {
+	sum, carry := bits.Add64(x, y, 0)
+	return sum, carry != 0
+}
+
+// safeMul returns x*y and whether the multiplication overflowed.
+func safeMul(x, y uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(x, y)
+	return lo, hi != 0
+}
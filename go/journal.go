@@ -0,0 +1,163 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+// journalEntry is a modification of the state that can be undone.
+type journalEntry interface {
+	revert(*MemoryStateDB)
+}
+
+// journal records the modifications of a MemoryStateDB so that they can be
+// rolled back to a snapshot.
+type journal struct {
+	entries []journalEntry
+}
+
+func (j *journal) append(entry journalEntry) {
+	j.entries = append(j.entries, entry)
+}
+
+// revert undoes, newest first, every entry recorded since the journal had
+// length snapshot.
+func (j *journal) revert(db *MemoryStateDB, snapshot int) {
+	for i := len(j.entries) - 1; i >= snapshot; i-- {
+		j.entries[i].revert(db)
+	}
+	j.entries = j.entries[:snapshot]
+}
+
+func (j *journal) length() int {
+	return len(j.entries)
+}
+
+type (
+	// an account was created where there was none
+	createObjectChange struct {
+		account Address
+	}
+	// an existing account was replaced by CreateAccount
+	resetObjectChange struct {
+		account Address
+		prev    *stateObject
+	}
+	createContractChange struct {
+		account Address
+	}
+	selfDestructChange struct {
+		account     Address
+		prev        bool // whether the account had already self-destructed
+		prevbalance *uint256.Int
+	}
+	balanceChange struct {
+		account Address
+		prev    *uint256.Int
+	}
+	nonceChange struct {
+		account Address
+		prev    uint64
+	}
+	codeChange struct {
+		account  Address
+		prevcode []byte
+		prevhash Hash
+	}
+	storageChange struct {
+		account  Address
+		key      Hash
+		prevalue Hash
+	}
+	transientStorageChange struct {
+		account  Address
+		key      Hash
+		prevalue Hash
+	}
+	addLogChange struct{}
+	refundChange struct {
+		prev uint64
+	}
+
+	accessListAddAccountChange struct {
+		address Address
+	}
+	accessListAddSlotChange struct {
+		address Address
+		slot    Hash
+	}
+	// the refund counter and start-of-transaction slot values were reset
+	// by Prepare
+	txResetChange struct {
+		prevRefund  uint64
+		prevOrigins map[Address]map[Hash]Hash
+	}
+
+	// the access list was replaced by Prepare
+	accessListResetChange struct {
+		prev *accessList
+	}
+)
+
+func (ch createObjectChange) revert(db *MemoryStateDB) {
+	delete(db.objects, ch.account)
+}
+
+func (ch resetObjectChange) revert(db *MemoryStateDB) {
+	db.objects[ch.account] = ch.prev
+}
+
+func (ch createContractChange) revert(db *MemoryStateDB) {
+	db.objects[ch.account].newContract = false
+}
+
+func (ch selfDestructChange) revert(db *MemoryStateDB) {
+	obj := db.objects[ch.account]
+	obj.selfDestructed = ch.prev
+	obj.balance = ch.prevbalance
+}
+
+func (ch balanceChange) revert(db *MemoryStateDB) {
+	db.objects[ch.account].balance = ch.prev
+}
+
+func (ch nonceChange) revert(db *MemoryStateDB) {
+	db.objects[ch.account].nonce = ch.prev
+}
+
+func (ch codeChange) revert(db *MemoryStateDB) {
+	obj := db.objects[ch.account]
+	obj.code, obj.codeHash = ch.prevcode, ch.prevhash
+}
+
+func (ch storageChange) revert(db *MemoryStateDB) {
+	db.objects[ch.account].setState(ch.key, ch.prevalue)
+}
+
+func (ch transientStorageChange) revert(db *MemoryStateDB) {
+	db.setTransientState(ch.account, ch.key, ch.prevalue)
+}
+
+func (ch addLogChange) revert(db *MemoryStateDB) {
+	db.logs = db.logs[:len(db.logs)-1]
+}
+
+func (ch refundChange) revert(db *MemoryStateDB) {
+	db.refund = ch.prev
+}
+
+func (ch accessListAddAccountChange) revert(db *MemoryStateDB) {
+	db.accessList.DeleteAddress(ch.address)
+}
+
+func (ch accessListAddSlotChange) revert(db *MemoryStateDB) {
+	db.accessList.DeleteSlot(ch.address, ch.slot)
+}
+
+func (ch txResetChange) revert(db *MemoryStateDB) {
+	db.refund = ch.prevRefund
+	for addr, origins := range ch.prevOrigins {
+		db.objects[addr].originStorage = origins
+	}
+}
+
+func (ch accessListResetChange) revert(db *MemoryStateDB) {
+	db.accessList = ch.prev
+}
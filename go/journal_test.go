@@ -0,0 +1,132 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestRevertToSnapshot(t *testing.T) {
+	var (
+		db   = NewMemoryStateDB()
+		addr = HexToAddress("0xaa")
+		key  = HexToHash("0x01")
+		one  = HexToHash("0x01")
+		two  = HexToHash("0x02")
+	)
+	db.AddBalance(addr, uint256.NewInt(10))
+	db.SetState(addr, key, one)
+
+	outer := db.Snapshot()
+	db.SubBalance(addr, uint256.NewInt(3))
+	db.SetNonce(addr, 1)
+	db.SetCode(addr, []byte{0x00})
+	db.SetState(addr, key, two)
+	db.SetTransientState(addr, key, one)
+	db.AddLog(&Log{Address: addr})
+
+	inner := db.Snapshot()
+	db.CreateAccount(addr)
+	db.AddBalance(HexToAddress("0xbb"), uint256.NewInt(1))
+	if db.GetState(addr, key) != (Hash{}) || db.GetNonce(addr) != 0 {
+		t.Fatal("CreateAccount did not reset the account")
+	}
+
+	db.RevertToSnapshot(inner)
+	if db.Exist(HexToAddress("0xbb")) {
+		t.Error("account created after the inner snapshot still exists")
+	}
+	if db.GetNonce(addr) != 1 || db.GetState(addr, key) != two || db.GetBalance(addr).Uint64() != 7 {
+		t.Error("inner revert undid changes made before the snapshot")
+	}
+
+	db.RevertToSnapshot(outer)
+	if got := db.GetBalance(addr).Uint64(); got != 10 {
+		t.Errorf("balance = %d, want 10", got)
+	}
+	if db.GetNonce(addr) != 0 || db.GetCodeHash(addr) != emptyCodeHash {
+		t.Error("nonce or code not reverted")
+	}
+	if db.GetState(addr, key) != one {
+		t.Error("storage not reverted")
+	}
+	if db.GetTransientState(addr, key) != (Hash{}) {
+		t.Error("transient storage not reverted")
+	}
+	if len(db.Logs()) != 0 {
+		t.Error("log not reverted")
+	}
+}
+
+func TestFailedRunRevertsStorage(t *testing.T) {
+	db := NewMemoryStateDB()
+	// SSTORE 1 at slot 0, then fail on INVALID
+	res := NewEVM(BlockContext{}, TxContext{}, db, Config{}).Run([]byte{byte(PUSH1), 1, byte(PUSH1), 0, byte(SSTORE), byte(INVALID)}, testGas)
+	if res.Success {
+		t.Fatal("expected failure")
+	}
+	if got := db.GetState(Address{}, Hash{}); got != (Hash{}) {
+		t.Errorf("slot 0 = %v, want zero", got)
+	}
+}
+
+func TestAccessListRevert(t *testing.T) {
+	var (
+		db   = NewMemoryStateDB()
+		addr = HexToAddress("0xaa")
+		key1 = HexToHash("0x01")
+		key2 = HexToHash("0x02")
+	)
+	db.AddAddressToAccessList(HexToAddress("0xbb"))
+
+	snapshot := db.Snapshot()
+	db.AddSlotToAccessList(addr, key1)
+	db.AddSlotToAccessList(addr, key2)
+	if addrOk, slotOk := db.SlotInAccessList(addr, key2); !addrOk || !slotOk {
+		t.Fatal("slot not added")
+	}
+
+	db.RevertToSnapshot(snapshot)
+	if db.AddressInAccessList(addr) {
+		t.Error("address still in the access list")
+	}
+	if _, slotOk := db.SlotInAccessList(addr, key1); slotOk {
+		t.Error("slot still in the access list")
+	}
+	if !db.AddressInAccessList(HexToAddress("0xbb")) {
+		t.Error("revert removed an address added before the snapshot")
+	}
+
+	// a slot added to a warm address leaves the address warm when reverted
+	db.AddAddressToAccessList(addr)
+	snapshot = db.Snapshot()
+	db.AddSlotToAccessList(addr, key1)
+	db.RevertToSnapshot(snapshot)
+	if addrOk, slotOk := db.SlotInAccessList(addr, key1); !addrOk || slotOk {
+		t.Errorf("got address %v, slot %v, want true, false", addrOk, slotOk)
+	}
+}
+
+func TestPrepareReverted(t *testing.T) {
+	db := NewMemoryStateDB()
+	before := HexToAddress("0xaa")
+	db.AddAddressToAccessList(before)
+	db.SetState(before, Hash{}, HexToHash("0x01"))
+	db.AddRefund(100)
+
+	snapshot := db.Snapshot()
+	dest := HexToAddress("0xbb")
+	db.Prepare(Cancun, HexToAddress("0xcc"), Address{}, &dest, nil, nil)
+	if db.GetRefund() != 0 || db.GetCommittedState(before, Hash{}) != HexToHash("0x01") {
+		t.Error("Prepare did not reset the refund counter and committed storage")
+	}
+	db.AddAddressToAccessList(HexToAddress("0xdd"))
+	db.RevertToSnapshot(snapshot)
+
+	if !db.AddressInAccessList(before) || db.AddressInAccessList(dest) || db.AddressInAccessList(HexToAddress("0xdd")) {
+		t.Error("revert did not restore the access list from before Prepare")
+	}
+	if db.GetRefund() != 100 || db.GetCommittedState(before, Hash{}) != (Hash{}) {
+		t.Error("revert did not restore the refund counter and committed storage from before Prepare")
+	}
+}
@@ -0,0 +1,689 @@
+package evm
+
+type (
+	executionFunc func(pc *uint64, evm *EVM, scope *ScopeContext) ([]byte, error)
+	// gasFunc returns the gas an instruction costs on top of its constant
+	// gas, given the memory size it needs.
+	gasFunc func(evm *EVM, scope *ScopeContext, memorySize uint64) (uint64, error)
+	// memorySizeFunc returns the memory size an instruction needs and
+	// whether that size overflows uint64.
+	memorySizeFunc func(*Stack) (size uint64, overflow bool)
+)
+
+type operation struct {
+	execute     executionFunc
+	constantGas uint64
+	dynamicGas  gasFunc
+	// minStack and maxStack bound the stack height the instruction runs at
+	minStack int
+	maxStack int
+	// memorySize returns the memory size required by the instruction
+	memorySize memorySizeFunc
+
+	halts  bool // stops execution of the frame
+	jumps  bool // sets the pc itself
+	writes bool // modifies state, so fails in read-only frames
+}
+
+// JumpTable maps every opcode to its operation. Undefined opcodes are nil.
+type JumpTable [256]*operation
+
+var (
+	berlinInstructionSet   = newBerlinInstructionSet()
+	londonInstructionSet   = newLondonInstructionSet()
+	shanghaiInstructionSet = newShanghaiInstructionSet()
+	cancunInstructionSet   = newCancunInstructionSet()
+)
+
+// instructionSetForFork returns the instruction set active at fork.
+func instructionSetForFork(fork Fork) *JumpTable {
+	switch {
+	case fork >= Cancun:
+		return &cancunInstructionSet
+	case fork >= Shanghai:
+		return &shanghaiInstructionSet
+	case fork >= London:
+		return &londonInstructionSet
+	default:
+		return &berlinInstructionSet
+	}
+}
+
+// newCancunInstructionSet adds TLOAD and TSTORE (EIP-1153), MCOPY
+// (EIP-5656), BLOBHASH (EIP-4844) and BLOBBASEFEE (EIP-7516), and limits
+// SELFDESTRUCT to new contracts (EIP-6780).
+func newCancunInstructionSet() JumpTable {
+	instructionSet := newShanghaiInstructionSet()
+	instructionSet[SELFDESTRUCT] = &operation{
+		execute:     opSelfdestruct6780,
+		constantGas: selfdestructGasEIP150,
+		dynamicGas:  gasSelfdestructEIP3529,
+		minStack:    minStack(1, 0),
+		maxStack:    maxStack(1, 0),
+		halts:       true,
+		writes:      true,
+	}
+	instructionSet[TLOAD] = &operation{
+		execute:     opTload,
+		constantGas: warmStorageReadCostEIP2929,
+		minStack:    minStack(1, 1),
+		maxStack:    maxStack(1, 1),
+	}
+	instructionSet[TSTORE] = &operation{
+		execute:     opTstore,
+		constantGas: warmStorageReadCostEIP2929,
+		minStack:    minStack(2, 0),
+		maxStack:    maxStack(2, 0),
+		writes:      true,
+	}
+	instructionSet[MCOPY] = &operation{
+		execute:     opMcopy,
+		constantGas: gasFastestStep,
+		minStack:    minStack(3, 0),
+		maxStack:    maxStack(3, 0),
+		memorySize:  memoryMcopy,
+		dynamicGas:  gasMcopy,
+	}
+	instructionSet[BLOBHASH] = &operation{
+		execute:     opBlobHash,
+		constantGas: gasFastestStep,
+		minStack:    minStack(1, 1),
+		maxStack:    maxStack(1, 1),
+	}
+	instructionSet[BLOBBASEFEE] = &operation{
+		execute:     opBlobBaseFee,
+		constantGas: gasQuickStep,
+		minStack:    minStack(0, 1),
+		maxStack:    maxStack(0, 1),
+	}
+	return instructionSet
+}
+
+// newShanghaiInstructionSet adds PUSH0 (EIP-3855) and charges CREATE and
+// CREATE2 per word of init code (EIP-3860).
+func newShanghaiInstructionSet() JumpTable {
+	instructionSet := newLondonInstructionSet()
+	instructionSet[CREATE].dynamicGas = gasCreateEip3860
+	instructionSet[CREATE2].dynamicGas = gasCreate2Eip3860
+	instructionSet[PUSH0] = &operation{
+		execute:     opPush0,
+		constantGas: gasQuickStep,
+		minStack:    minStack(0, 1),
+		maxStack:    maxStack(0, 1),
+	}
+	return instructionSet
+}
+
+// newLondonInstructionSet adds BASEFEE (EIP-3198) and lowers SSTORE and
+// SELFDESTRUCT refunds (EIP-3529).
+func newLondonInstructionSet() JumpTable {
+	instructionSet := newBerlinInstructionSet()
+	instructionSet[SSTORE].dynamicGas = gasSStoreEIP3529
+	instructionSet[SELFDESTRUCT].dynamicGas = gasSelfdestructEIP3529
+	instructionSet[BASEFEE] = &operation{
+		execute:     opBaseFee,
+		constantGas: gasQuickStep,
+		minStack:    minStack(0, 1),
+		maxStack:    maxStack(0, 1),
+	}
+	return instructionSet
+}
+
+// newBerlinInstructionSet returns the instructions of the oldest supported
+// fork. Later forks start from a fresh copy of it and add their changes.
+func newBerlinInstructionSet() JumpTable {
+	tbl := JumpTable{
+		STOP: {
+			execute:     opStop,
+			constantGas: 0,
+			minStack:    minStack(0, 0),
+			maxStack:    maxStack(0, 0),
+			halts:       true,
+		},
+		ADD: {
+			execute:     opAdd,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		MUL: {
+			execute:     opMul,
+			constantGas: gasFastStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SUB: {
+			execute:     opSub,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		DIV: {
+			execute:     opDiv,
+			constantGas: gasFastStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SDIV: {
+			execute:     opSdiv,
+			constantGas: gasFastStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		MOD: {
+			execute:     opMod,
+			constantGas: gasFastStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SMOD: {
+			execute:     opSmod,
+			constantGas: gasFastStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		ADDMOD: {
+			execute:     opAddmod,
+			constantGas: gasMidStep,
+			minStack:    minStack(3, 1),
+			maxStack:    maxStack(3, 1),
+		},
+		MULMOD: {
+			execute:     opMulmod,
+			constantGas: gasMidStep,
+			minStack:    minStack(3, 1),
+			maxStack:    maxStack(3, 1),
+		},
+		EXP: {
+			execute:     opExp,
+			constantGas: gasSlowStep,
+			dynamicGas:  gasExp,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SIGNEXTEND: {
+			execute:     opSignExtend,
+			constantGas: gasFastStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		LT: {
+			execute:     opLt,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		GT: {
+			execute:     opGt,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SLT: {
+			execute:     opSlt,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SGT: {
+			execute:     opSgt,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		EQ: {
+			execute:     opEq,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		ISZERO: {
+			execute:     opIszero,
+			constantGas: gasFastestStep,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		AND: {
+			execute:     opAnd,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		XOR: {
+			execute:     opXor,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		OR: {
+			execute:     opOr,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		NOT: {
+			execute:     opNot,
+			constantGas: gasFastestStep,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		BYTE: {
+			execute:     opByte,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SHL: {
+			execute:     opSHL,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SHR: {
+			execute:     opSHR,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SAR: {
+			execute:     opSAR,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+		},
+		SHA3: {
+			execute:     opSha3,
+			constantGas: sha3Gas,
+			minStack:    minStack(2, 1),
+			maxStack:    maxStack(2, 1),
+			memorySize:  memorySha3,
+			dynamicGas:  gasSha3,
+		},
+		ADDRESS: {
+			execute:     opAddress,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		BALANCE: {
+			execute:     opBalance,
+			constantGas: warmStorageReadCostEIP2929,
+			dynamicGas:  gasEip2929AccountCheck,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		ORIGIN: {
+			execute:     opOrigin,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		CALLER: {
+			execute:     opCaller,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		CALLVALUE: {
+			execute:     opCallValue,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		CALLDATALOAD: {
+			execute:     opCallDataLoad,
+			constantGas: gasFastestStep,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		CALLDATASIZE: {
+			execute:     opCallDataSize,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		CALLDATACOPY: {
+			execute:     opCallDataCopy,
+			constantGas: gasFastestStep,
+			minStack:    minStack(3, 0),
+			maxStack:    maxStack(3, 0),
+			memorySize:  memoryCallDataCopy,
+			dynamicGas:  gasCallDataCopy,
+		},
+		CODESIZE: {
+			execute:     opCodeSize,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		CODECOPY: {
+			execute:     opCodeCopy,
+			constantGas: gasFastestStep,
+			minStack:    minStack(3, 0),
+			maxStack:    maxStack(3, 0),
+			memorySize:  memoryCodeCopy,
+			dynamicGas:  gasCodeCopy,
+		},
+		GASPRICE: {
+			execute:     opGasprice,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		EXTCODESIZE: {
+			execute:     opExtCodeSize,
+			constantGas: warmStorageReadCostEIP2929,
+			dynamicGas:  gasEip2929AccountCheck,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		EXTCODECOPY: {
+			execute:     opExtCodeCopy,
+			constantGas: warmStorageReadCostEIP2929,
+			minStack:    minStack(4, 0),
+			maxStack:    maxStack(4, 0),
+			memorySize:  memoryExtCodeCopy,
+			dynamicGas:  gasExtCodeCopyEIP2929,
+		},
+		RETURNDATASIZE: {
+			execute:     opReturnDataSize,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		RETURNDATACOPY: {
+			execute:     opReturnDataCopy,
+			constantGas: gasFastestStep,
+			minStack:    minStack(3, 0),
+			maxStack:    maxStack(3, 0),
+			memorySize:  memoryReturnDataCopy,
+			dynamicGas:  gasReturnDataCopy,
+		},
+		EXTCODEHASH: {
+			execute:     opExtCodeHash,
+			constantGas: warmStorageReadCostEIP2929,
+			dynamicGas:  gasEip2929AccountCheck,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		BLOCKHASH: {
+			execute:     opBlockhash,
+			constantGas: gasExtStep,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		COINBASE: {
+			execute:     opCoinbase,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		TIMESTAMP: {
+			execute:     opTimestamp,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		NUMBER: {
+			execute:     opNumber,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		DIFFICULTY: {
+			execute:     opDifficulty,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		GASLIMIT: {
+			execute:     opGasLimit,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		CHAINID: {
+			execute:     opChainID,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		SELFBALANCE: {
+			execute:     opSelfBalance,
+			constantGas: gasFastStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		POP: {
+			execute:     opPop,
+			constantGas: gasQuickStep,
+			minStack:    minStack(1, 0),
+			maxStack:    maxStack(1, 0),
+		},
+		MLOAD: {
+			execute:     opMload,
+			constantGas: gasFastestStep,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+			memorySize:  memoryMLoad,
+			dynamicGas:  gasMLoad,
+		},
+		MSTORE: {
+			execute:     opMstore,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 0),
+			maxStack:    maxStack(2, 0),
+			memorySize:  memoryMStore,
+			dynamicGas:  gasMStore,
+		},
+		MSTORE8: {
+			execute:     opMstore8,
+			constantGas: gasFastestStep,
+			minStack:    minStack(2, 0),
+			maxStack:    maxStack(2, 0),
+			memorySize:  memoryMStore8,
+			dynamicGas:  gasMStore8,
+		},
+		SLOAD: {
+			execute:     opSload,
+			constantGas: 0,
+			dynamicGas:  gasSLoadEIP2929,
+			minStack:    minStack(1, 1),
+			maxStack:    maxStack(1, 1),
+		},
+		SSTORE: {
+			execute:    opSstore,
+			dynamicGas: gasSStoreEIP2929,
+			minStack:   minStack(2, 0),
+			maxStack:   maxStack(2, 0),
+			writes:     true,
+		},
+		JUMP: {
+			execute:     opJump,
+			constantGas: gasMidStep,
+			minStack:    minStack(1, 0),
+			maxStack:    maxStack(1, 0),
+			jumps:       true,
+		},
+		JUMPI: {
+			execute:     opJumpi,
+			constantGas: gasSlowStep,
+			minStack:    minStack(2, 0),
+			maxStack:    maxStack(2, 0),
+			jumps:       true,
+		},
+		PC: {
+			execute:     opPc,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		MSIZE: {
+			execute:     opMsize,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		GAS: {
+			execute:     opGas,
+			constantGas: gasQuickStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		},
+		JUMPDEST: {
+			execute:     opJumpdest,
+			constantGas: jumpdestGas,
+			minStack:    minStack(0, 0),
+			maxStack:    maxStack(0, 0),
+		},
+		CREATE: {
+			execute:     opCreate,
+			constantGas: createGas,
+			minStack:    minStack(3, 1),
+			maxStack:    maxStack(3, 1),
+			memorySize:  memoryCreate,
+			dynamicGas:  gasCreate,
+			writes:      true,
+		},
+		CALL: {
+			execute:     opCall,
+			constantGas: warmStorageReadCostEIP2929,
+			minStack:    minStack(7, 1),
+			maxStack:    maxStack(7, 1),
+			memorySize:  memoryCall,
+			dynamicGas:  gasCallEIP2929,
+		},
+		CALLCODE: {
+			execute:     opCallCode,
+			constantGas: warmStorageReadCostEIP2929,
+			minStack:    minStack(7, 1),
+			maxStack:    maxStack(7, 1),
+			memorySize:  memoryCall,
+			dynamicGas:  gasCallCodeEIP2929,
+		},
+		RETURN: {
+			execute:    opReturn,
+			minStack:   minStack(2, 0),
+			maxStack:   maxStack(2, 0),
+			memorySize: memoryReturn,
+			dynamicGas: gasReturn,
+			halts:      true,
+		},
+		DELEGATECALL: {
+			execute:     opDelegateCall,
+			constantGas: warmStorageReadCostEIP2929,
+			minStack:    minStack(6, 1),
+			maxStack:    maxStack(6, 1),
+			memorySize:  memoryDelegateCall,
+			dynamicGas:  gasDelegateCallEIP2929,
+		},
+		CREATE2: {
+			execute:     opCreate2,
+			constantGas: createGas,
+			minStack:    minStack(4, 1),
+			maxStack:    maxStack(4, 1),
+			memorySize:  memoryCreate2,
+			dynamicGas:  gasCreate2,
+			writes:      true,
+		},
+		STATICCALL: {
+			execute:     opStaticCall,
+			constantGas: warmStorageReadCostEIP2929,
+			minStack:    minStack(6, 1),
+			maxStack:    maxStack(6, 1),
+			memorySize:  memoryStaticCall,
+			dynamicGas:  gasStaticCallEIP2929,
+		},
+		REVERT: {
+			execute:    opRevert,
+			minStack:   minStack(2, 0),
+			maxStack:   maxStack(2, 0),
+			memorySize: memoryRevert,
+			dynamicGas: gasRevert,
+			halts:      true,
+		},
+		SELFDESTRUCT: {
+			execute:     opSelfdestruct,
+			constantGas: selfdestructGasEIP150,
+			dynamicGas:  gasSelfdestructEIP2929,
+			minStack:    minStack(1, 0),
+			maxStack:    maxStack(1, 0),
+			halts:       true,
+			writes:      true,
+		},
+		INVALID: {
+			execute:  opInvalid,
+			minStack: minStack(0, 0),
+			maxStack: maxStack(0, 0),
+			halts:    true,
+		},
+	}
+
+	for i := 1; i <= 32; i++ {
+		tbl[PUSH1+OpCode(i-1)] = &operation{
+			execute:     makePush(uint64(i)),
+			constantGas: gasFastestStep,
+			minStack:    minStack(0, 1),
+			maxStack:    maxStack(0, 1),
+		}
+	}
+	for i := 1; i <= 16; i++ {
+		tbl[DUP1+OpCode(i-1)] = &operation{
+			execute:     makeDup(i),
+			constantGas: gasFastestStep,
+			minStack:    minDupStack(i),
+			maxStack:    maxDupStack(i),
+		}
+		tbl[SWAP1+OpCode(i-1)] = &operation{
+			execute:     makeSwap(i),
+			constantGas: gasFastestStep,
+			minStack:    minSwapStack(i + 1),
+			maxStack:    maxSwapStack(i + 1),
+		}
+	}
+	for i := 0; i <= 4; i++ {
+		tbl[LOG0+OpCode(i)] = &operation{
+			execute:    makeLog(i),
+			minStack:   minStack(i+2, 0),
+			maxStack:   maxStack(i+2, 0),
+			memorySize: memoryLog,
+			dynamicGas: makeGasLog(uint64(i)),
+			writes:     true,
+		}
+	}
+	return tbl
+}
+
+// OpInfo describes an instruction for tooling such as disassemblers and
+// static analyzers.
+type OpInfo struct {
+	OpCode      OpCode
+	ConstantGas uint64
+	DynamicGas  bool // costs more gas depending on its operands
+	UsesMemory  bool // may expand memory
+	MinStack    int
+	MaxStack    int
+	Halts       bool
+	Jumps       bool
+	Writes      bool
+}
+
+// LookupInstruction describes op as defined at fork. It returns false if op
+// is undefined at that fork.
+func LookupInstruction(fork Fork, op OpCode) (OpInfo, bool) {
+	operation := instructionSetForFork(fork)[op]
+	if operation == nil {
+		return OpInfo{}, false
+	}
+	return OpInfo{
+		OpCode:      op,
+		ConstantGas: operation.constantGas,
+		DynamicGas:  operation.dynamicGas != nil,
+		UsesMemory:  operation.memorySize != nil,
+		MinStack:    operation.minStack,
+		MaxStack:    operation.maxStack,
+		Halts:       operation.halts,
+		Jumps:       operation.jumps,
+		Writes:      operation.writes,
+	}, true
+}
@@ -0,0 +1,53 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookupInstruction(t *testing.T) {
+	info, ok := LookupInstruction(Berlin, SWAP3)
+	if !ok {
+		t.Fatal("SWAP3 undefined")
+	}
+	if info.MinStack != 4 || info.MaxStack != stackLimit || info.ConstantGas != gasFastestStep {
+		t.Errorf("unexpected SWAP3 info: %+v", info)
+	}
+	if info, _ := LookupInstruction(Berlin, JUMPI); !info.Jumps || info.Halts {
+		t.Errorf("unexpected JUMPI flags: %+v", info)
+	}
+	if info, _ := LookupInstruction(Berlin, MSTORE); !info.UsesMemory {
+		t.Errorf("MSTORE should use memory: %+v", info)
+	}
+	if _, ok := LookupInstruction(Berlin, 0x0c); ok {
+		t.Error("0x0c should be undefined")
+	}
+}
+
+func TestForkInstructionSets(t *testing.T) {
+	if _, ok := LookupInstruction(Berlin, PUSH0); ok {
+		t.Error("PUSH0 defined before Shanghai")
+	}
+	if _, ok := LookupInstruction(Shanghai, PUSH0); !ok {
+		t.Error("PUSH0 undefined in Shanghai")
+	}
+
+	code := []byte{byte(PUSH0)}
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{Fork: Berlin}).Run(code, testGas)
+	var invalidOp *ErrInvalidOpCode
+	if res.Success || !errors.As(res.Err, &invalidOp) {
+		t.Errorf("PUSH0 on Berlin: expected invalid opcode, got %v", res.Err)
+	}
+	res = NewEVM(BlockContext{}, TxContext{}, nil, Config{Fork: Shanghai}).Run(code, testGas)
+	if !res.Success || len(res.Stack) != 1 || !res.Stack[0].IsZero() {
+		t.Errorf("PUSH0 on Shanghai: got %v %v", res.Stack, res.Err)
+	}
+}
+
+func TestShiftBeyondUint64(t *testing.T) {
+	// PUSH1 1, PUSH9 1<<64, SHL: the shift amount must not be truncated
+	res := runHex(t, "600168010000000000000000"+"1b")
+	if !res.Success || !res.Stack[0].IsZero() {
+		t.Errorf("got %v %v, want 0", res.Stack, res.Err)
+	}
+}
@@ -0,0 +1,13 @@
+package evm
+
+// Log is an event emitted by the LOG0...LOG4 instructions. Address, Topics
+// and Data are the consensus fields stored in receipts; PC and Depth record
+// where the log was emitted.
+type Log struct {
+	Address Address
+	Topics  []Hash
+	Data    []byte
+
+	PC    uint64 // of the LOG instruction
+	Depth int    // of the emitting frame, 1 for the outermost one
+}
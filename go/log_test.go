@@ -0,0 +1,53 @@
+package evm
+
+import "testing"
+
+func TestBloom(t *testing.T) {
+	positive := []string{"testtest", "test", "hallo", "other"}
+	negative := []string{"tes", "lo"}
+
+	var bloom Bloom
+	for _, data := range positive {
+		bloom.Add([]byte(data))
+	}
+	for _, data := range positive {
+		if !bloom.Test([]byte(data)) {
+			t.Errorf("expected %q to be in the bloom", data)
+		}
+	}
+	for _, data := range negative {
+		if bloom.Test([]byte(data)) {
+			t.Errorf("did not expect %q to be in the bloom", data)
+		}
+	}
+}
+
+func TestLogs(t *testing.T) {
+	self := HexToAddress("0x1000000000000000000000000000000000000001")
+	// MSTORE8 0xaa at 0, then LOG1 with topic 0x11 of the first byte
+	code := []byte{
+		byte(PUSH1), 0xaa, byte(PUSH1), 0, byte(MSTORE8),
+		byte(PUSH1), 0x11, byte(PUSH1), 1, byte(PUSH1), 0, byte(LOG1),
+	}
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{}).RunContract(NewContract(Address{}, self, nil, code, nil, testGas))
+	if !res.Success || len(res.Logs) != 1 {
+		t.Fatalf("got %d logs, err %v", len(res.Logs), res.Err)
+	}
+	l := res.Logs[0]
+	if l.Address != self || len(l.Data) != 1 || l.Data[0] != 0xaa || l.Topics[0] != HexToHash("0x11") {
+		t.Errorf("unexpected log %+v", l)
+	}
+	if l.PC != 11 || l.Depth != 1 {
+		t.Errorf("log emitted at pc %d depth %d, want pc 11 depth 1", l.PC, l.Depth)
+	}
+	if !res.Bloom.Test(self.Bytes()) || !res.Bloom.Test(l.Topics[0].Bytes()) {
+		t.Error("bloom is missing the address or topic")
+	}
+
+	// logs of a failed execution are discarded
+	db := NewMemoryStateDB()
+	res = NewEVM(BlockContext{}, TxContext{}, db, Config{}).Run(append(code, byte(INVALID)), testGas)
+	if res.Success || len(res.Logs) != 0 || len(db.Logs()) != 0 || res.Bloom != (Bloom{}) {
+		t.Errorf("failed execution kept %d logs", len(db.Logs()))
+	}
+}
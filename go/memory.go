@@ -1,50 +1,109 @@
-package main
+package evm
 
 import (
 	"github.com/holiman/uint256"
 	"golang.org/x/crypto/sha3"
 )
 
+// Memory is the byte-addressed memory of a frame. Accessors never grow
+// memory: the interpreter resizes it before every instruction from the
+// instruction's memory size function, after checking the size against
+// Config.MaxMemory.
 type Memory struct {
-	data []byte
+	data        []byte
+	lastGasCost uint64 // total expansion gas paid for data so far
 }
 
 func NewMemory() *Memory {
 	return &Memory{}
 }
 
+// Set copies value into memory at offset, zero-filling the rest of size
+// bytes when value is shorter. Copy-style instructions use it to write
+// calldata, code and return data, which are zero-padded past their end.
+func (m *Memory) Set(offset, size uint64, value []byte) {
+	if size == 0 {
+		return
+	}
+	dst := m.data[offset : offset+size]
+	n := copy(dst, value)
+	for i := n; i < len(dst); i++ {
+		dst[i] = 0
+	}
+}
+
+// Put writes val as a 32-byte big-endian word at offset.
 func (m *Memory) Put(offset uint64, val *uint256.Int) {
-	m.expandIfNeeded(offset, 32)
 	val.WriteToSlice(m.data[offset : offset+32])
 }
 
+// PutByte writes a single byte at offset.
 func (m *Memory) PutByte(offset uint64, val byte) {
-	m.expandIfNeeded(offset, 1)
 	m.data[offset] = val
 }
 
+// Get reads the 32-byte word at offset.
 func (m *Memory) Get(offset uint64) *uint256.Int {
-	m.expandIfNeeded(offset, 32)
 	return uint256.NewInt(0).SetBytes(m.data[offset : offset+32])
 }
 
+// GetCopy returns a copy of size bytes at offset, safe to keep after
+// memory changes.
+func (m *Memory) GetCopy(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	cpy := make([]byte, size)
+	copy(cpy, m.data[offset:offset+size])
+	return cpy
+}
+
+// GetPtr returns the size bytes at offset without copying them. The slice
+// aliases memory and is only valid until the next write or resize.
+func (m *Memory) GetPtr(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	return m.data[offset : offset+size]
+}
+
+// Copy moves length bytes from src to dst within memory. The ranges may
+// overlap.
+func (m *Memory) Copy(dst, src, length uint64) {
+	if length == 0 {
+		return
+	}
+	copy(m.data[dst:dst+length], m.data[src:src+length])
+}
+
+// Sha3 hashes size bytes at offset. A zero size hashes the empty string
+// whatever the offset, since it touches no memory.
 func (m *Memory) Sha3(offset, size uint64) *uint256.Int {
-	m.expandIfNeeded(offset, size)
 	h := sha3.NewLegacyKeccak256()
-	h.Write(m.data[offset : offset+size])
+	if size > 0 {
+		h.Write(m.data[offset : offset+size])
+	}
 	return uint256.NewInt(0).SetBytes(h.Sum(nil))
 }
 
-func (m *Memory) expandIfNeeded(offset, size uint64) {
-	lastByte := offset + size
-	if lastByte%32 != 0 {
-		lastByte += 32 - (lastByte % 32)
-	}
-	if uint64(len(m.data)) < lastByte {
-		m.data = append(m.data, make([]byte, lastByte-uint64(len(m.data)))...)
+// Resize grows memory to hold size bytes, rounded up to whole words, and
+// returns the number of words it added. It never shrinks memory.
+func (m *Memory) Resize(size uint64) (newWords uint64) {
+	size = toWordSize(size) * 32
+	if uint64(len(m.data)) >= size {
+		return 0
 	}
+	newWords = (size - uint64(len(m.data))) / 32
+	m.data = append(m.data, make([]byte, size-uint64(len(m.data)))...)
+	return newWords
 }
 
+// Len returns the size of memory in bytes, always a multiple of 32.
 func (m *Memory) Len() uint64 {
 	return uint64(len(m.data))
 }
+
+// Data returns the whole memory. The slice aliases memory.
+func (m *Memory) Data() []byte {
+	return m.data
+}
@@ -0,0 +1,98 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryOffsets(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		// PUSH1 1, PUSH5 1<<32, MSTORE
+		{name: "MSTORE past the cap", code: "6001" + "640100000000" + "52", err: ErrMemoryLimit},
+		// PUSH1 1, PUSH8 max uint64, MSTORE
+		{name: "MSTORE offset overflows with size", code: "6001" + "67ffffffffffffffff" + "52", err: ErrGasUintOverflow},
+		// PUSH1 1, PUSH9 1<<64, MSTORE8
+		{name: "MSTORE8 offset beyond uint64", code: "6001" + "68010000000000000000" + "53", err: ErrGasUintOverflow},
+		// PUSH9 1<<64, PUSH1 0, SHA3
+		{name: "SHA3 size beyond uint64", code: "68010000000000000000" + "6000" + "20", err: ErrGasUintOverflow},
+		// PUSH1 0, PUSH9 1<<64, SHA3: zero-sized reads touch no memory
+		{name: "SHA3 of nothing at a huge offset", code: "6000" + "68010000000000000000" + "20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runHex(t, tt.code)
+			if tt.err == nil {
+				if !res.Success {
+					t.Fatalf("unexpected failure: %v", res.Err)
+				}
+				return
+			}
+			if res.Success || !errors.Is(res.Err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, res.Err)
+			}
+		})
+	}
+}
+
+func TestMaxMemoryConfig(t *testing.T) {
+	// PUSH1 0, PUSH2 0x0400, MSTORE needs 1056 bytes
+	code := []byte{0x60, 0x00, 0x61, 0x04, 0x00, 0x52}
+	res := NewEVM(BlockContext{}, TxContext{}, nil, Config{MaxMemory: 1024}).Run(code, testGas)
+	if !errors.Is(res.Err, ErrMemoryLimit) {
+		t.Fatalf("expected memory limit, got %v", res.Err)
+	}
+	res = NewEVM(BlockContext{}, TxContext{}, nil, Config{MaxMemory: 1056}).Run(code, testGas)
+	if !res.Success {
+		t.Fatalf("unexpected failure: %v", res.Err)
+	}
+}
+
+func TestMemoryAPI(t *testing.T) {
+	m := NewMemory()
+	if words := m.Resize(33); words != 2 {
+		t.Fatalf("Resize(33) added %d words, want 2", words)
+	}
+	if m.Len() != 64 {
+		t.Fatalf("Resize(33) grew memory to %d bytes, want 64", m.Len())
+	}
+	if words := m.Resize(64); words != 0 {
+		t.Fatalf("Resize(64) after Resize(33) added %d words, want 0", words)
+	}
+
+	m.Set(0, 4, []byte{1, 2, 3, 4})
+	m.Set(2, 4, []byte{9}) // zero-pads the rest
+	if got := m.GetCopy(0, 6); string(got) != string([]byte{1, 2, 9, 0, 0, 0}) {
+		t.Fatalf("Set: got %x", got)
+	}
+
+	m.Set(0, 4, []byte{1, 2, 3, 4})
+	m.Copy(1, 0, 4) // overlapping, forwards
+	if got := m.GetPtr(0, 5); string(got) != string([]byte{1, 1, 2, 3, 4}) {
+		t.Fatalf("Copy: got %x", got)
+	}
+	m.Copy(1000, 2000, 0)
+	if m.GetPtr(1000, 0) != nil || m.GetCopy(1000, 0) != nil {
+		t.Fatal("zero-sized reads should not touch memory")
+	}
+}
+
+func TestMcopy(t *testing.T) {
+	// PUSH32 0x01..20, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, PUSH1 1, MCOPY, PUSH1 0, MLOAD, MSIZE
+	code := "7f0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20" +
+		"600052" + "6020600060015e" + "600051" + "59"
+	res := runHex(t, code)
+	if !res.Success {
+		t.Fatal(res.Err)
+	}
+	want := HexToHash("0x010102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	if got := res.Stack[1].Bytes32(); got != want {
+		t.Errorf("MLOAD after MCOPY: got %x", got)
+	}
+	if res.Stack[0].Uint64() != 64 {
+		t.Errorf("MSIZE after MCOPY: got %v, want 64", &res.Stack[0])
+	}
+}
@@ -0,0 +1,19 @@
+package evm
+
+// ActivePrecompiles returns the addresses of the precompiled contracts at
+// fork. They are warm from the start of every transaction (EIP-2929).
+// Their execution is not implemented: calling them runs no code.
+func ActivePrecompiles(fork Fork) []Address {
+	last := byte(0x09) // ecrecover to blake2f
+	if fork >= Cancun {
+		last = 0x0a // KZG point evaluation (EIP-4844)
+	}
+	if fork >= Prague {
+		last = 0x11 // BLS12-381 operations (EIP-2537)
+	}
+	addrs := make([]Address, 0, last)
+	for i := byte(1); i <= last; i++ {
+		addrs = append(addrs, BytesToAddress([]byte{i}))
+	}
+	return addrs
+}
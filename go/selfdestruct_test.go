@@ -0,0 +1,105 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// selfdestructTo returns code that self-destructs to beneficiary.
+func selfdestructTo(beneficiary Address) []byte {
+	return append(append([]byte{byte(PUSH20)}, beneficiary.Bytes()...), byte(SELFDESTRUCT))
+}
+
+func TestSelfdestruct(t *testing.T) {
+	var (
+		victim = HexToAddress("0xdead")
+		heir   = HexToAddress("0xa1c3")
+	)
+	tests := []struct {
+		name        string
+		fork        Fork
+		beneficiary Address
+		wantDeleted bool
+		wantVictim  uint64 // balance before the end of the transaction
+		wantHeir    uint64
+	}{
+		{"Shanghai", Shanghai, heir, true, 0, 7},
+		{"Shanghai to self", Shanghai, victim, true, 0, 0},
+		{"Cancun", Cancun, heir, false, 0, 7},
+		{"Cancun to self", Cancun, victim, false, 7, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryStateDB()
+			db.AddBalance(victim, uint256.NewInt(7))
+			db.SetCode(victim, selfdestructTo(tt.beneficiary))
+
+			evm := NewEVM(BlockContext{}, TxContext{}, db, Config{Fork: tt.fork})
+			if _, _, err := evm.Call(Address{}, victim, nil, testGas, new(uint256.Int)); err != nil {
+				t.Fatal(err)
+			}
+			if db.GetBalance(victim).Uint64() != tt.wantVictim || db.GetBalance(heir).Uint64() != tt.wantHeir {
+				t.Errorf("balances %v %v, want %d %d", db.GetBalance(victim), db.GetBalance(heir), tt.wantVictim, tt.wantHeir)
+			}
+			// deletion waits for the end of the transaction
+			if db.GetCodeSize(victim) == 0 {
+				t.Error("code removed before the end of the transaction")
+			}
+			db.Finalise()
+			if deleted := !db.Exist(victim); deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestSelfdestructNewContract(t *testing.T) {
+	var (
+		db      = NewMemoryStateDB()
+		creator = HexToAddress("0xc0")
+		heir    = HexToAddress("0xa1c3")
+	)
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{Fork: Cancun})
+	// init code that self-destructs straight away
+	_, addr, _, err := evm.Create(creator, selfdestructTo(heir), testGas, new(uint256.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.HasSelfDestructed(addr) {
+		t.Fatal("contract created in the transaction did not self-destruct")
+	}
+	db.Finalise()
+	if db.Exist(addr) {
+		t.Error("contract still exists after the transaction")
+	}
+}
+
+func TestSelfdestructReverted(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		victim = HexToAddress("0xdead")
+	)
+	db.AddBalance(victim, uint256.NewInt(7))
+	snapshot := db.Snapshot()
+	db.SelfDestruct(victim)
+	db.RevertToSnapshot(snapshot)
+	if db.HasSelfDestructed(victim) || db.GetBalance(victim).Uint64() != 7 {
+		t.Error("self-destruct not reverted")
+	}
+}
+
+func TestEagerSelfDestruct(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		victim = HexToAddress("0xdead")
+	)
+	db.SetCode(victim, selfdestructTo(HexToAddress("0xa1c3")))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{EagerSelfDestruct: true})
+	if _, _, err := evm.Call(Address{}, victim, nil, testGas, new(uint256.Int)); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetCodeSize(victim) != 0 || !db.HasSelfDestructed(victim) {
+		t.Error("account not destroyed at once")
+	}
+}
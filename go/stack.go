@@ -0,0 +1,80 @@
+package evm
+
+import (
+	"sync"
+
+	"github.com/holiman/uint256"
+)
+
+var stackPool = sync.Pool{
+	New: func() interface{} {
+		return &Stack{data: make([]uint256.Int, 0, stackLimit)}
+	},
+}
+
+// Stack is the operand stack of a frame. The backing array is allocated
+// once with room for stackLimit items and the top of the stack is the last
+// element, so pushes and pops never move the other items. Callers must
+// check the stack bounds of an instruction before running it.
+type Stack struct {
+	data []uint256.Int
+}
+
+func newstack() *Stack {
+	return stackPool.Get().(*Stack)
+}
+
+func returnStack(s *Stack) {
+	s.data = s.data[:0]
+	stackPool.Put(s)
+}
+
+func (st *Stack) push(d *uint256.Int) {
+	st.data = append(st.data, *d)
+}
+
+func (st *Stack) pop() (ret uint256.Int) {
+	ret = st.data[len(st.data)-1]
+	st.data = st.data[:len(st.data)-1]
+	return
+}
+
+// peek returns the top item, which instructions overwrite with their result.
+func (st *Stack) peek() *uint256.Int {
+	return &st.data[len(st.data)-1]
+}
+
+// swap exchanges the top item with the n-th item below it.
+func (st *Stack) swap(n int) {
+	st.data[len(st.data)-n-1], st.data[len(st.data)-1] = st.data[len(st.data)-1], st.data[len(st.data)-n-1]
+}
+
+// dup pushes a copy of the n-th item, counting the top as 1.
+func (st *Stack) dup(n int) {
+	st.push(&st.data[len(st.data)-n])
+}
+
+// Len returns the number of items on the stack.
+func (st *Stack) Len() int {
+	return len(st.data)
+}
+
+// Back returns the n-th item from the top, counting the top as 0.
+func (st *Stack) Back(n int) *uint256.Int {
+	return &st.data[len(st.data)-n-1]
+}
+
+// Data returns the items bottom first. The slice is only valid until the
+// stack is modified.
+func (st *Stack) Data() []uint256.Int {
+	return st.data
+}
+
+// TopFirst returns a copy of the items with the top of the stack first.
+func (st *Stack) TopFirst() []uint256.Int {
+	items := make([]uint256.Int, len(st.data))
+	for i := range st.data {
+		items[i] = st.data[len(st.data)-1-i]
+	}
+	return items
+}
@@ -0,0 +1,31 @@
+package evm
+
+// stackLimit is the maximum number of items the stack can hold.
+const stackLimit = 1024
+
+// minStack and maxStack give the range of stack heights an instruction
+// popping pops items and pushing push items can run at. Below the minimum
+// it underflows, above the maximum it would overflow the stack.
+func minStack(pops, push int) int {
+	return pops
+}
+
+func maxStack(pops, push int) int {
+	return stackLimit + pops - push
+}
+
+func minSwapStack(n int) int {
+	return minStack(n, n)
+}
+
+func maxSwapStack(n int) int {
+	return maxStack(n, n)
+}
+
+func minDupStack(n int) int {
+	return minStack(n, n+1)
+}
+
+func maxDupStack(n int) int {
+	return maxStack(n, n+1)
+}
@@ -0,0 +1,47 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestStack(t *testing.T) {
+	st := newstack()
+	defer returnStack(st)
+
+	for i := uint64(1); i <= 3; i++ {
+		st.push(uint256.NewInt(i))
+	}
+	st.dup(3)  // 1 2 3 1
+	st.swap(2) // 1 1 3 2
+
+	want := []uint64{2, 3, 1, 1}
+	got := st.TopFirst()
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if !got[i].Eq(uint256.NewInt(w)) {
+			t.Errorf("item %d: got %v, want %d", i, &got[i], w)
+		}
+	}
+	if st.Back(0).Uint64() != 2 || st.Back(3).Uint64() != 1 {
+		t.Errorf("Back returned wrong items: %v %v", st.Back(0), st.Back(3))
+	}
+	if v := st.pop(); v.Uint64() != 2 || st.Len() != 3 {
+		t.Errorf("pop returned %v, %d items left", &v, st.Len())
+	}
+}
+
+func TestStackPoolReuse(t *testing.T) {
+	st := newstack()
+	st.push(uint256.NewInt(1))
+	returnStack(st)
+
+	st = newstack()
+	defer returnStack(st)
+	if st.Len() != 0 || cap(st.data) != stackLimit {
+		t.Fatalf("pooled stack has %d items and capacity %d", st.Len(), cap(st.data))
+	}
+}
@@ -0,0 +1,89 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+// StateDB gives the interpreter access to the world state. Implementations
+// treat accounts that were never created as empty: zero balance and nonce,
+// no code and a zero code hash.
+type StateDB interface {
+	CreateAccount(Address)
+	// CreateContract marks an account as created by the current
+	// transaction, which matters to SelfDestruct6780.
+	CreateContract(Address)
+
+	SubBalance(Address, *uint256.Int)
+	AddBalance(Address, *uint256.Int)
+	GetBalance(Address) *uint256.Int
+
+	GetNonce(Address) uint64
+	SetNonce(Address, uint64)
+
+	GetCodeHash(Address) Hash
+	GetCode(Address) []byte
+	SetCode(Address, []byte)
+	GetCodeSize(Address) int
+
+	GetState(Address, Hash) Hash
+	SetState(Address, Hash, Hash)
+	// GetCommittedState returns the value the slot had at the start of
+	// the transaction, which SSTORE gas depends on (EIP-2200).
+	GetCommittedState(Address, Hash) Hash
+
+	// Transient storage (EIP-1153) is discarded at the end of the
+	// transaction.
+	GetTransientState(Address, Hash) Hash
+	SetTransientState(Address, Hash, Hash)
+
+	AddLog(*Log)
+	// Logs returns the logs added so far, oldest first.
+	Logs() []*Log
+
+	// The refund counter accumulates the gas refunded at the end of the
+	// transaction. Changes to it are undone by RevertToSnapshot.
+	AddRefund(uint64)
+	SubRefund(uint64)
+	GetRefund() uint64
+
+	// SelfDestruct clears the balance of the account and marks it for
+	// deletion at the end of the transaction.
+	SelfDestruct(Address)
+	HasSelfDestructed(Address) bool
+	// SelfDestruct6780 is SelfDestruct for accounts created in the current
+	// transaction, and does nothing for other accounts (EIP-6780).
+	SelfDestruct6780(Address)
+
+	// Exist reports whether the account exists, even if it is empty.
+	Exist(Address) bool
+	// Empty reports whether the account is empty as defined by EIP-161:
+	// no code, and a zero nonce and balance.
+	Empty(Address) bool
+
+	// Prepare starts a transaction from sender to dest, which is nil for
+	// a contract creation. It resets the access list (EIP-2929) to the
+	// sender, dest, the precompiles and the entries of list, and from
+	// Shanghai on also the coinbase (EIP-3651). It also clears the refund
+	// counter and takes the current slot values as those at the start of
+	// the transaction. RevertToSnapshot undoes all of it.
+	Prepare(fork Fork, sender, coinbase Address, dest *Address, precompiles []Address, list AccessList)
+
+	// The access list holds the addresses and slots that are warm in the
+	// current transaction. Additions, and resets by Prepare, are undone by
+	// RevertToSnapshot.
+	AddressInAccessList(Address) bool
+	SlotInAccessList(Address, Hash) (addressOk bool, slotOk bool)
+	AddAddressToAccessList(Address)
+	// AddSlotToAccessList adds the slot and its address.
+	AddSlotToAccessList(Address, Hash)
+
+	// Snapshot returns an identifier for the current state, and
+	// RevertToSnapshot undoes every modification made since that snapshot,
+	// including added logs.
+	Snapshot() int
+	RevertToSnapshot(int)
+
+	// Finalise ends the transaction: accounts that self-destructed are
+	// deleted, transient storage and the refund counter are cleared, the
+	// current storage becomes the committed storage and earlier snapshots
+	// can no longer be reverted.
+	Finalise()
+}
@@ -0,0 +1,460 @@
+package evm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+// emptyCodeHash is the keccak256 digest of empty code.
+var emptyCodeHash = Keccak256Hash(nil)
+
+type stateObject struct {
+	balance  *uint256.Int
+	nonce    uint64
+	code     []byte
+	codeHash Hash
+	storage  map[Hash]Hash
+	// originStorage holds the value slots had at the start of the
+	// transaction, for the slots written since
+	originStorage map[Hash]Hash
+
+	newContract    bool // created by the current transaction
+	selfDestructed bool // to be deleted at the end of the transaction
+}
+
+func newObject() *stateObject {
+	return &stateObject{
+		balance:  new(uint256.Int),
+		codeHash: emptyCodeHash,
+		storage:  make(map[Hash]Hash),
+
+		originStorage: make(map[Hash]Hash),
+	}
+}
+
+func (s *stateObject) empty() bool {
+	return s.nonce == 0 && s.balance.IsZero() && s.codeHash == emptyCodeHash
+}
+
+// setState stores value at key. Zero values are not kept.
+func (s *stateObject) setState(key, value Hash) {
+	if value == (Hash{}) {
+		delete(s.storage, key)
+	} else {
+		s.storage[key] = value
+	}
+}
+
+// MemoryStateDB is a StateDB that keeps all accounts in memory. Every
+// modification is journaled, so that it can be undone with
+// RevertToSnapshot.
+type MemoryStateDB struct {
+	objects   map[Address]*stateObject
+	transient map[Address]map[Hash]Hash
+	logs      []*Log
+	refund    uint64
+
+	accessList *accessList
+	journal    journal
+}
+
+// NewMemoryStateDB returns an empty in-memory state.
+func NewMemoryStateDB() *MemoryStateDB {
+	return &MemoryStateDB{
+		objects:    make(map[Address]*stateObject),
+		transient:  make(map[Address]map[Hash]Hash),
+		accessList: newAccessList(),
+	}
+}
+
+// NewMemoryStateDBFromAlloc returns an in-memory state holding the accounts
+// of alloc. The returned state has an empty journal.
+func NewMemoryStateDBFromAlloc(alloc GenesisAlloc) *MemoryStateDB {
+	db := NewMemoryStateDB()
+	for addr, account := range alloc {
+		db.CreateAccount(addr)
+		if account.Balance != nil {
+			db.AddBalance(addr, account.Balance)
+		}
+		db.SetNonce(addr, account.Nonce)
+		db.SetCode(addr, account.Code)
+		for key, value := range account.Storage {
+			db.SetState(addr, key, value)
+		}
+	}
+	db.Finalise()
+	return db
+}
+
+// getOrNewObject returns the account at addr, creating it if it does not
+// exist.
+func (db *MemoryStateDB) getOrNewObject(addr Address) *stateObject {
+	obj := db.objects[addr]
+	if obj == nil {
+		obj = newObject()
+		db.objects[addr] = obj
+		db.journal.append(createObjectChange{account: addr})
+	}
+	return obj
+}
+
+// CreateAccount creates an empty account at addr. The balance of an
+// account that already exists is carried over.
+func (db *MemoryStateDB) CreateAccount(addr Address) {
+	obj := newObject()
+	if prev := db.objects[addr]; prev != nil {
+		obj.balance.Set(prev.balance)
+		db.journal.append(resetObjectChange{account: addr, prev: prev})
+	} else {
+		db.journal.append(createObjectChange{account: addr})
+	}
+	db.objects[addr] = obj
+}
+
+func (db *MemoryStateDB) CreateContract(addr Address) {
+	obj := db.getOrNewObject(addr)
+	if !obj.newContract {
+		db.journal.append(createContractChange{account: addr})
+		obj.newContract = true
+	}
+}
+
+func (db *MemoryStateDB) SubBalance(addr Address, amount *uint256.Int) {
+	obj := db.getOrNewObject(addr)
+	db.journal.append(balanceChange{account: addr, prev: obj.balance})
+	obj.balance = new(uint256.Int).Sub(obj.balance, amount)
+}
+
+func (db *MemoryStateDB) AddBalance(addr Address, amount *uint256.Int) {
+	obj := db.getOrNewObject(addr)
+	db.journal.append(balanceChange{account: addr, prev: obj.balance})
+	obj.balance = new(uint256.Int).Add(obj.balance, amount)
+}
+
+// GetBalance returns a copy of the balance of addr.
+func (db *MemoryStateDB) GetBalance(addr Address) *uint256.Int {
+	if obj := db.objects[addr]; obj != nil {
+		return new(uint256.Int).Set(obj.balance)
+	}
+	return new(uint256.Int)
+}
+
+func (db *MemoryStateDB) GetNonce(addr Address) uint64 {
+	if obj := db.objects[addr]; obj != nil {
+		return obj.nonce
+	}
+	return 0
+}
+
+func (db *MemoryStateDB) SetNonce(addr Address, nonce uint64) {
+	obj := db.getOrNewObject(addr)
+	db.journal.append(nonceChange{account: addr, prev: obj.nonce})
+	obj.nonce = nonce
+}
+
+func (db *MemoryStateDB) GetCodeHash(addr Address) Hash {
+	if obj := db.objects[addr]; obj != nil {
+		return obj.codeHash
+	}
+	return Hash{}
+}
+
+func (db *MemoryStateDB) GetCode(addr Address) []byte {
+	if obj := db.objects[addr]; obj != nil {
+		return obj.code
+	}
+	return nil
+}
+
+func (db *MemoryStateDB) SetCode(addr Address, code []byte) {
+	obj := db.getOrNewObject(addr)
+	db.journal.append(codeChange{account: addr, prevcode: obj.code, prevhash: obj.codeHash})
+	obj.code = code
+	obj.codeHash = Keccak256Hash(code)
+}
+
+func (db *MemoryStateDB) GetCodeSize(addr Address) int {
+	return len(db.GetCode(addr))
+}
+
+func (db *MemoryStateDB) GetState(addr Address, key Hash) Hash {
+	if obj := db.objects[addr]; obj != nil {
+		return obj.storage[key]
+	}
+	return Hash{}
+}
+
+// GetCommittedState returns the value the slot had at the start of the
+// transaction.
+func (db *MemoryStateDB) GetCommittedState(addr Address, key Hash) Hash {
+	obj := db.objects[addr]
+	if obj == nil {
+		return Hash{}
+	}
+	if value, dirty := obj.originStorage[key]; dirty {
+		return value
+	}
+	return obj.storage[key]
+}
+
+func (db *MemoryStateDB) SetState(addr Address, key, value Hash) {
+	obj := db.getOrNewObject(addr)
+	prev := obj.storage[key]
+	if prev == value {
+		return
+	}
+	if _, dirty := obj.originStorage[key]; !dirty {
+		obj.originStorage[key] = prev
+	}
+	db.journal.append(storageChange{account: addr, key: key, prevalue: prev})
+	obj.setState(key, value)
+}
+
+func (db *MemoryStateDB) GetTransientState(addr Address, key Hash) Hash {
+	return db.transient[addr][key]
+}
+
+func (db *MemoryStateDB) SetTransientState(addr Address, key, value Hash) {
+	prev := db.GetTransientState(addr, key)
+	if prev == value {
+		return
+	}
+	db.journal.append(transientStorageChange{account: addr, key: key, prevalue: prev})
+	db.setTransientState(addr, key, value)
+}
+
+func (db *MemoryStateDB) setTransientState(addr Address, key, value Hash) {
+	slots := db.transient[addr]
+	if slots == nil {
+		slots = make(map[Hash]Hash)
+		db.transient[addr] = slots
+	}
+	if value == (Hash{}) {
+		delete(slots, key)
+	} else {
+		slots[key] = value
+	}
+}
+
+func (db *MemoryStateDB) AddLog(log *Log) {
+	db.journal.append(addLogChange{})
+	db.logs = append(db.logs, log)
+}
+
+// Logs returns the logs added since the state was created, oldest first.
+func (db *MemoryStateDB) Logs() []*Log {
+	return db.logs
+}
+
+func (db *MemoryStateDB) AddRefund(gas uint64) {
+	db.journal.append(refundChange{prev: db.refund})
+	db.refund += gas
+}
+
+// SubRefund panics if gas is more than the counter holds, which would be a
+// bug in the gas schedule.
+func (db *MemoryStateDB) SubRefund(gas uint64) {
+	if gas > db.refund {
+		panic(fmt.Sprintf("refund counter below zero (gas: %d > refund: %d)", gas, db.refund))
+	}
+	db.journal.append(refundChange{prev: db.refund})
+	db.refund -= gas
+}
+
+func (db *MemoryStateDB) GetRefund() uint64 {
+	return db.refund
+}
+
+func (db *MemoryStateDB) SelfDestruct(addr Address) {
+	obj := db.objects[addr]
+	if obj == nil {
+		return
+	}
+	db.journal.append(selfDestructChange{account: addr, prev: obj.selfDestructed, prevbalance: obj.balance})
+	obj.selfDestructed = true
+	obj.balance = new(uint256.Int)
+}
+
+func (db *MemoryStateDB) HasSelfDestructed(addr Address) bool {
+	obj := db.objects[addr]
+	return obj != nil && obj.selfDestructed
+}
+
+func (db *MemoryStateDB) SelfDestruct6780(addr Address) {
+	if obj := db.objects[addr]; obj != nil && obj.newContract {
+		db.SelfDestruct(addr)
+	}
+}
+
+func (db *MemoryStateDB) Exist(addr Address) bool {
+	return db.objects[addr] != nil
+}
+
+func (db *MemoryStateDB) Empty(addr Address) bool {
+	obj := db.objects[addr]
+	return obj == nil || obj.empty()
+}
+
+// Prepare resets the access list, the refund counter and the
+// start-of-transaction slot values for a new transaction. Reverting to a
+// snapshot taken before restores them.
+func (db *MemoryStateDB) Prepare(fork Fork, sender, coinbase Address, dest *Address, precompiles []Address, list AccessList) {
+	origins := make(map[Address]map[Hash]Hash)
+	for addr, obj := range db.objects {
+		if len(obj.originStorage) > 0 {
+			origins[addr] = obj.originStorage
+			obj.originStorage = make(map[Hash]Hash)
+		}
+	}
+	db.journal.append(txResetChange{prevRefund: db.refund, prevOrigins: origins})
+	db.refund = 0
+
+	db.journal.append(accessListResetChange{prev: db.accessList})
+	db.accessList = newAccessList()
+	db.accessList.AddAddress(sender)
+	if dest != nil {
+		db.accessList.AddAddress(*dest)
+	}
+	for _, addr := range precompiles {
+		db.accessList.AddAddress(addr)
+	}
+	for _, tuple := range list {
+		db.accessList.AddAddress(tuple.Address)
+		for _, key := range tuple.StorageKeys {
+			db.accessList.AddSlot(tuple.Address, key)
+		}
+	}
+	if fork >= Shanghai {
+		db.accessList.AddAddress(coinbase)
+	}
+}
+
+func (db *MemoryStateDB) AddressInAccessList(addr Address) bool {
+	return db.accessList.ContainsAddress(addr)
+}
+
+func (db *MemoryStateDB) SlotInAccessList(addr Address, slot Hash) (addressOk bool, slotOk bool) {
+	return db.accessList.Contains(addr, slot)
+}
+
+func (db *MemoryStateDB) AddAddressToAccessList(addr Address) {
+	if db.accessList.AddAddress(addr) {
+		db.journal.append(accessListAddAccountChange{address: addr})
+	}
+}
+
+func (db *MemoryStateDB) AddSlotToAccessList(addr Address, slot Hash) {
+	addrMod, slotMod := db.accessList.AddSlot(addr, slot)
+	if addrMod {
+		// reverting the slot alone leaves the address in the list, so
+		// the address gets an entry of its own
+		db.journal.append(accessListAddAccountChange{address: addr})
+	}
+	if slotMod {
+		db.journal.append(accessListAddSlotChange{address: addr, slot: slot})
+	}
+}
+
+// Snapshot returns an identifier for the current state. Snapshots must be
+// reverted in the reverse order they were taken.
+func (db *MemoryStateDB) Snapshot() int {
+	return db.journal.length()
+}
+
+// RevertToSnapshot undoes every modification made since the snapshot with
+// the given id was taken.
+func (db *MemoryStateDB) RevertToSnapshot(id int) {
+	if id < 0 || id > db.journal.length() {
+		panic(fmt.Sprintf("snapshot %d cannot be reverted", id))
+	}
+	db.journal.revert(db, id)
+}
+
+func (db *MemoryStateDB) Finalise() {
+	for addr, obj := range db.objects {
+		if obj.selfDestructed {
+			delete(db.objects, addr)
+		} else {
+			obj.newContract = false
+			obj.originStorage = make(map[Hash]Hash)
+		}
+	}
+	db.transient = make(map[Address]map[Hash]Hash)
+	db.refund = 0
+	db.journal = journal{}
+}
+
+// GenesisAccount is the initial state of an account.
+type GenesisAccount struct {
+	Balance *uint256.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[Hash]Hash
+}
+
+// GenesisAlloc maps addresses to their initial state. It decodes from the
+// `state` objects of evm.json.
+type GenesisAlloc map[Address]GenesisAccount
+
+// UnmarshalJSON decodes an account whose balance, nonce and storage are
+// hex strings. The code is either a hex string or an object with the hex in
+// its "bin" field, as in evm.json.
+func (ga *GenesisAccount) UnmarshalJSON(input []byte) error {
+	var dec struct {
+		Balance string
+		Nonce   string
+		Code    json.RawMessage
+		Storage map[string]string
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	var account GenesisAccount
+	if dec.Balance != "" {
+		b, err := decodeHex(dec.Balance)
+		if err != nil || len(b) > 32 {
+			return fmt.Errorf("invalid balance %q", dec.Balance)
+		}
+		account.Balance = new(uint256.Int).SetBytes(b)
+	}
+	if dec.Nonce != "" {
+		n, err := uint256.FromHex(dec.Nonce)
+		if err != nil || !n.IsUint64() {
+			return fmt.Errorf("invalid nonce %q", dec.Nonce)
+		}
+		account.Nonce = n.Uint64()
+	}
+	if len(dec.Code) > 0 {
+		var code string
+		if err := json.Unmarshal(dec.Code, &code); err != nil {
+			var obj struct{ Bin string }
+			if err := json.Unmarshal(dec.Code, &obj); err != nil {
+				return fmt.Errorf("invalid code: %v", err)
+			}
+			code = obj.Bin
+		}
+		b, err := decodeHex(code)
+		if err != nil {
+			return fmt.Errorf("invalid code: %v", err)
+		}
+		account.Code = b
+	}
+	if len(dec.Storage) > 0 {
+		account.Storage = make(map[Hash]Hash, len(dec.Storage))
+		for k, v := range dec.Storage {
+			key, err := decodeHex(k)
+			if err != nil || len(key) > HashLength {
+				return fmt.Errorf("invalid storage key %q", k)
+			}
+			value, err := decodeHex(v)
+			if err != nil || len(value) > HashLength {
+				return fmt.Errorf("invalid storage value %q", v)
+			}
+			account.Storage[BytesToHash(key)] = BytesToHash(value)
+		}
+	}
+	*ga = account
+	return nil
+}
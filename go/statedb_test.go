@@ -0,0 +1,111 @@
+package evm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestGenesisAllocJSON(t *testing.T) {
+	input := `{
+		"0x1000000000000000000000000000000000000aaa": {"balance": "0x100", "code": {"asm": "PUSH1 1", "bin": "6001"}},
+		"0xbb": {"nonce": "0x2", "code": "0xFFFF"}
+	}`
+	var alloc GenesisAlloc
+	if err := json.Unmarshal([]byte(input), &alloc); err != nil {
+		t.Fatal(err)
+	}
+	db := NewMemoryStateDBFromAlloc(alloc)
+
+	aaa := HexToAddress("0x1000000000000000000000000000000000000aaa")
+	if got := db.GetBalance(aaa); got.Uint64() != 0x100 {
+		t.Errorf("balance = %v, want 0x100", got)
+	}
+	if got := db.GetCodeSize(aaa); got != 2 {
+		t.Errorf("code size = %d, want 2", got)
+	}
+	bb := HexToAddress("0xbb")
+	if db.GetNonce(bb) != 2 || db.GetCodeHash(bb) != Keccak256Hash([]byte{0xff, 0xff}) {
+		t.Errorf("unexpected account 0xbb: nonce %d, code %x", db.GetNonce(bb), db.GetCode(bb))
+	}
+
+	if err := json.Unmarshal([]byte(`{"0xzz": {}}`), &alloc); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+}
+
+func TestExistAndEmpty(t *testing.T) {
+	db := NewMemoryStateDB()
+	addr := HexToAddress("0x01")
+	if db.Exist(addr) || !db.Empty(addr) || db.GetCodeHash(addr) != (Hash{}) {
+		t.Fatal("unexpected state for a missing account")
+	}
+	db.CreateAccount(addr)
+	if !db.Exist(addr) || !db.Empty(addr) || db.GetCodeHash(addr) != emptyCodeHash {
+		t.Fatal("unexpected state for a new account")
+	}
+	db.AddBalance(addr, uint256.NewInt(1))
+	if db.Empty(addr) {
+		t.Fatal("account with balance is empty")
+	}
+
+	// EXTCODEHASH of a non-empty account without code is the empty code hash
+	code := []byte{byte(PUSH1), 0x01, byte(EXTCODEHASH)}
+	res := NewEVM(BlockContext{}, TxContext{}, db, Config{}).Run(code, testGas)
+	if !res.Success || res.Stack[0] != *new(uint256.Int).SetBytes(emptyCodeHash[:]) {
+		t.Errorf("got %v %v", res.Stack, res.Err)
+	}
+}
+
+func TestPrepareAccessList(t *testing.T) {
+	var (
+		sender   = HexToAddress("0x01ff")
+		dest     = HexToAddress("0x02ff")
+		coinbase = HexToAddress("0xc0")
+		listed   = HexToAddress("0x03ff")
+		key      = HexToHash("0x01")
+	)
+	for _, fork := range []Fork{London, Shanghai} {
+		db := NewMemoryStateDB()
+		db.AddAddressToAccessList(HexToAddress("0xdead"))
+		db.Prepare(fork, sender, coinbase, &dest, ActivePrecompiles(fork), AccessList{{Address: listed, StorageKeys: []Hash{key}}})
+
+		for _, addr := range []Address{sender, dest, listed, HexToAddress("0x01"), HexToAddress("0x09")} {
+			if !db.AddressInAccessList(addr) {
+				t.Errorf("%v: %v is not warm", fork, addr)
+			}
+		}
+		if _, slotOk := db.SlotInAccessList(listed, key); !slotOk {
+			t.Errorf("%v: listed slot is not warm", fork)
+		}
+		if db.AddressInAccessList(HexToAddress("0xdead")) {
+			t.Errorf("%v: access list of the previous transaction kept", fork)
+		}
+		if got, want := db.AddressInAccessList(coinbase), fork >= Shanghai; got != want {
+			t.Errorf("%v: coinbase warm = %v, want %v", fork, got, want)
+		}
+	}
+}
+
+func TestCommittedState(t *testing.T) {
+	var (
+		db   = NewMemoryStateDB()
+		addr = HexToAddress("0xaa")
+		key  = HexToHash("0x01")
+		one  = HexToHash("0x01")
+		two  = HexToHash("0x02")
+	)
+	db.SetState(addr, key, one)
+	if got := db.GetCommittedState(addr, key); got != (Hash{}) {
+		t.Errorf("committed before Finalise = %v, want zero", got)
+	}
+	db.Finalise()
+	db.SetState(addr, key, two)
+	if got := db.GetCommittedState(addr, key); got != one {
+		t.Errorf("committed = %v, want %v", got, one)
+	}
+	if got := db.GetState(addr, key); got != two {
+		t.Errorf("current = %v, want %v", got, two)
+	}
+}
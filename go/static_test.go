@@ -0,0 +1,88 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestStaticCallWriteProtection(t *testing.T) {
+	var (
+		caller = HexToAddress("0xc0")
+		target = HexToAddress("0xaa")
+	)
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"SSTORE", "6001600055"},
+		{"TSTORE", "600160005d"},
+		{"LOG0", "60006000a0"},
+		{"CREATE", "600060006000f0"},
+		{"SELFDESTRUCT", "6000ff"},
+		{"CALL with value", "6000808080" + "6001" + "6000" + "5a" + "f1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryStateDB()
+			db.AddBalance(target, uint256.NewInt(1))
+			db.SetCode(target, fromHex(tt.code))
+			evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+
+			if _, _, err := evm.StaticCall(caller, target, nil, testGas); !errors.Is(err, ErrWriteProtection) {
+				t.Errorf("expected ErrWriteProtection, got %v", err)
+			}
+
+			// the same code runs in a normal call
+			if _, _, err := evm.Call(caller, target, nil, testGas, new(uint256.Int)); err != nil {
+				t.Errorf("normal call failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestStaticCallNested(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		target = HexToAddress("0xaa")
+		inner  = HexToAddress("0xbb")
+	)
+	// CALL inner with 100000 gas and no value, and RETURN the success flag
+	db.SetCode(target, fromHex("6000808080808073"+hex.EncodeToString(inner.Bytes())+"620186a0f1"+"600052"+"60206000f3"))
+	db.SetCode(inner, fromHex("6001600055"))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+
+	// the SSTORE of inner fails, even though it was reached by a CALL
+	ret, _, err := evm.StaticCall(HexToAddress("0xc0"), target, nil, testGas)
+	if err != nil || len(ret) != 32 || ret[31] != 0 {
+		t.Errorf("got %x %v, want a zero success flag", ret, err)
+	}
+	if db.GetState(inner, Hash{}) != (Hash{}) {
+		t.Error("inner frame wrote storage")
+	}
+
+	ret, _, err = evm.Call(HexToAddress("0xc0"), target, nil, testGas, new(uint256.Int))
+	if err != nil || len(ret) != 32 || ret[31] != 1 {
+		t.Errorf("normal call: got %x %v, want a success flag", ret, err)
+	}
+}
+
+func TestStaticCallReads(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		target = HexToAddress("0xaa")
+	)
+	// SLOAD slot 0, CALL without value to an empty account, RETURN the word
+	db.SetState(target, Hash{}, HexToHash("0x2a"))
+	db.SetCode(target, fromHex("600054"+"6000808080806001"+"5a"+"f1"+"50"+"600052"+"60206000f3"))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	ret, _, err := evm.StaticCall(HexToAddress("0xc0"), target, nil, testGas)
+	if err != nil || new(uint256.Int).SetBytes(ret).Uint64() != 0x2a {
+		t.Errorf("got %x %v", ret, err)
+	}
+	if evm.readOnly {
+		t.Error("read-only flag leaked out of the static call")
+	}
+}
@@ -19,10 +19,14 @@ type testCase struct {
 		Bin string
 		Asm string
 	}
+	Tx     json.RawMessage `json:",omitempty"`
+	Block  json.RawMessage `json:",omitempty"`
+	State  json.RawMessage `json:",omitempty"`
 	Expect struct {
 		Stack   []string
 		Success bool
 		Return  string
+		Logs    json.RawMessage `json:",omitempty"`
 	}
 
 	FnName  string
@@ -31,9 +35,10 @@ type testCase struct {
 }
 
 var tmpl = template.Must(template.New("").Parse(`
-package main
+package evm
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -50,10 +55,33 @@ type testCase struct {
 		Bin string
 		Asm string
 	}
+	Tx struct {
+		To       string
+		From     string
+		Origin   string
+		Gasprice string
+		Value    string
+		Data     string
+	}
+	Block struct {
+		Coinbase   string
+		Timestamp  string
+		Number     string
+		Difficulty string
+		Gaslimit   string
+		Basefee    string
+		Chainid    string
+	}
+	State  GenesisAlloc
 	Expect struct {
 		Stack   []string
 		Success bool
 		Return  string
+		Logs    []struct {
+			Address string
+			Data    string
+			Topics  []string
+		}
 	}
 }
 
@@ -86,10 +114,39 @@ func runTest(t *testing.T, index int, payload []byte) {
 		expectedStack = append(expectedStack, *i)
 	}
 
-	// Note: as the test cases get more complex, you'll need to modify this
-	// to pass down more arguments to the evm function and return more than
-	// just the stack.
-	success, stack := evm(bin)
+	blockCtx := BlockContext{
+		CanTransfer: func(StateDB, Address, *uint256.Int) bool { return true },
+		Transfer:    mintingTransfer,
+		Coinbase:   HexToAddress(test.Block.Coinbase),
+		GasLimit:   hexToInt(test.Block.Gaslimit).Uint64(),
+		Number:     hexToInt(test.Block.Number).Uint64(),
+		Time:       hexToInt(test.Block.Timestamp).Uint64(),
+		Difficulty: hexToInt(test.Block.Difficulty),
+		BaseFee:    hexToInt(test.Block.Basefee),
+	}
+	txCtx := TxContext{
+		Origin:   HexToAddress(test.Tx.Origin),
+		GasPrice: hexToInt(test.Tx.Gasprice),
+	}
+	config := Config{
+		ChainID:           hexToInt(test.Block.Chainid).Uint64(),
+		EagerSelfDestruct: true,
+		NoGasMetering:     true,
+	}
+
+	contract := NewContract(
+		HexToAddress(test.Tx.From),
+		HexToAddress(test.Tx.To),
+		hexToInt(test.Tx.Value),
+		bin,
+		fromHex(test.Tx.Data),
+		blockCtx.GasLimit,
+	)
+
+	statedb := NewMemoryStateDBFromAlloc(test.State)
+
+	result := NewEVM(blockCtx, txCtx, statedb, config).RunContract(contract)
+	success, stack := result.Success, result.Stack
 
 	match := len(stack) == len(expectedStack)
 	if match {
@@ -98,17 +155,65 @@ func runTest(t *testing.T, index int, payload []byte) {
 		}
 	}
 	match = match && (success == test.Expect.Success)
+	if test.Expect.Return != "" {
+		match = match && bytes.Equal(result.ReturnData, fromHex(test.Expect.Return))
+	}
+
+	if test.Expect.Logs != nil {
+		logsMatch := len(result.Logs) == len(test.Expect.Logs)
+		for i := 0; logsMatch && i < len(result.Logs); i++ {
+			want, got := test.Expect.Logs[i], result.Logs[i]
+			logsMatch = got.Address == HexToAddress(want.Address) &&
+				bytes.Equal(got.Data, fromHex(want.Data)) &&
+				len(got.Topics) == len(want.Topics)
+			for j := 0; logsMatch && j < len(want.Topics); j++ {
+				logsMatch = got.Topics[j] == HexToHash(want.Topics[j])
+			}
+		}
+		if !logsMatch {
+			fmt.Printf("Instructions: \n%v\n", test.Code.Asm)
+			fmt.Printf("Expected logs: %+v\n", test.Expect.Logs)
+			fmt.Printf("Got logs:      %v\n\n", toLogStrings(result.Logs))
+			fmt.Printf("Progress: %v/%v\n\n", index, len(payload))
+			log.Fatal("Logs mismatch")
+		}
+	}
 
 	if !match {
 		fmt.Printf("Instructions: \n%v\n", test.Code.Asm)
 		fmt.Printf("Expected: success=%v, stack=%v\n", test.Expect.Success, toStrings(expectedStack))
 		fmt.Printf("Got:      success=%v, stack=%v\n\n", success, toStrings(stack))
+		if test.Expect.Return != "" {
+			fmt.Printf("Expected return: %v\n", test.Expect.Return)
+			fmt.Printf("Got return:      %x\n\n", result.ReturnData)
+		}
+		if result.Err != nil {
+			fmt.Printf("Error: %v\n\n", result.Err)
+		}
 		fmt.Printf("Hint: %v\n\n", test.Hint)
 		fmt.Printf("Progress: %v/%v\n\n", index, len(payload))
 		log.Fatal("Stack mismatch")
 	}
 }
 
+// mintingTransfer credits the recipient without debiting the sender. The
+// test cases send value from accounts they never fund.
+func mintingTransfer(db StateDB, sender, recipient Address, amount *uint256.Int) {
+	db.AddBalance(recipient, amount)
+}
+
+func hexToInt(s string) *uint256.Int {
+	return new(uint256.Int).SetBytes(fromHex(s))
+}
+
+func toLogStrings(logs []*Log) []string {
+	var strings []string
+	for _, l := range logs {
+		strings = append(strings, fmt.Sprintf("{Address:%v Data:%x Topics:%v}", l.Address, l.Data, l.Topics))
+	}
+	return strings
+}
+
 func toStrings(stack []uint256.Int) []string {
 	var strings []string
 	for _, s := range stack {
@@ -0,0 +1,215 @@
+package evm
+
+import (
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+// Message is a transaction as the EVM executes it. To is nil for a
+// contract creation, in which case Data is the init code.
+type Message struct {
+	From       Address
+	To         *Address
+	Value      *uint256.Int
+	GasLimit   uint64
+	GasPrice   *uint256.Int
+	Data       []byte
+	AccessList AccessList
+	BlobHashes []Hash
+}
+
+// TransactionResult is the outcome of a transaction, with the fields of its
+// receipt. GasUsed is what the sender pays for: the gas left after
+// execution is returned, along with Refund, but never less than the
+// calldata floor is charged (EIP-7623). Err is the reason execution failed;
+// the transaction is still valid and its gas is paid.
+type TransactionResult struct {
+	Success         bool
+	GasUsed         uint64
+	Refund          uint64
+	ContractAddress Address // of a contract creation
+	ReturnData      []byte
+	Logs            []*Log
+	Bloom           Bloom
+	Err             error
+}
+
+// IntrinsicGas returns the gas a transaction pays before execution starts:
+// the base cost, its calldata, the words of init code from Shanghai on
+// (EIP-3860) and its access list (EIP-2930).
+func IntrinsicGas(data []byte, accessList AccessList, isContractCreation bool, fork Fork) (uint64, error) {
+	gas := txGas
+	if isContractCreation {
+		gas = txGasContractCreation
+	}
+	dataLen := uint64(len(data))
+	if dataLen > 0 {
+		nz := uint64(0)
+		for _, b := range data {
+			if b != 0 {
+				nz++
+			}
+		}
+		z := dataLen - nz
+
+		if (maxUint64-gas)/txDataNonZeroGasEIP2028 < nz {
+			return 0, ErrGasUintOverflow
+		}
+		gas += nz * txDataNonZeroGasEIP2028
+		if (maxUint64-gas)/txDataZeroGas < z {
+			return 0, ErrGasUintOverflow
+		}
+		gas += z * txDataZeroGas
+
+		if isContractCreation && fork >= Shanghai {
+			lenWords := toWordSize(dataLen)
+			if (maxUint64-gas)/initCodeWordGas < lenWords {
+				return 0, ErrGasUintOverflow
+			}
+			gas += lenWords * initCodeWordGas
+		}
+	}
+	if accessList != nil {
+		addresses, keys := uint64(len(accessList)), uint64(accessList.StorageKeys())
+		if (maxUint64-gas)/txAccessListAddressGas < addresses {
+			return 0, ErrGasUintOverflow
+		}
+		gas += addresses * txAccessListAddressGas
+		if (maxUint64-gas)/txAccessListStorageKeyGas < keys {
+			return 0, ErrGasUintOverflow
+		}
+		gas += keys * txAccessListStorageKeyGas
+	}
+	return gas, nil
+}
+
+// FloorDataGas returns the least a transaction with the given calldata pays
+// from Prague on, whatever it uses (EIP-7623).
+func FloorDataGas(data []byte) (uint64, error) {
+	var z uint64
+	for _, b := range data {
+		if b == 0 {
+			z++
+		}
+	}
+	tokens := (uint64(len(data))-z)*txTokenPerNonZeroByte + z
+	if (maxUint64-txGas)/txCostFloorPerToken < tokens {
+		return 0, ErrGasUintOverflow
+	}
+	return txGas + tokens*txCostFloorPerToken, nil
+}
+
+// ApplyMessage executes msg as a transaction: it checks that the gas limit
+// covers the intrinsic gas, buys the gas from the sender, runs the call or
+// creation, returns the unused gas and refund to the sender, pays the
+// coinbase and finalises the state.
+//
+// An error means the transaction is invalid and the state is untouched.
+// Execution failures are reported in TransactionResult.Err.
+func (evm *EVM) ApplyMessage(msg *Message) (*TransactionResult, error) {
+	result, err := evm.applyMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	evm.StateDB.Finalise()
+	return result, nil
+}
+
+// applyMessage is ApplyMessage without finalising the state, so that its
+// changes can still be reverted to a snapshot.
+func (evm *EVM) applyMessage(msg *Message) (*TransactionResult, error) {
+	var (
+		fork               = evm.Config.Fork
+		isContractCreation = msg.To == nil
+		value              = msg.Value
+		gasPrice           = msg.GasPrice
+	)
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	if gasPrice == nil {
+		gasPrice = new(uint256.Int)
+	}
+
+	gas, err := IntrinsicGas(msg.Data, msg.AccessList, isContractCreation, fork)
+	if err != nil {
+		return nil, err
+	}
+	if msg.GasLimit < gas {
+		return nil, fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, msg.GasLimit, gas)
+	}
+	var floorDataGas uint64
+	if fork >= Prague {
+		if floorDataGas, err = FloorDataGas(msg.Data); err != nil {
+			return nil, err
+		}
+		if msg.GasLimit < floorDataGas {
+			return nil, fmt.Errorf("%w: have %d, want %d", ErrFloorDataGas, msg.GasLimit, floorDataGas)
+		}
+	}
+	if isContractCreation && fork >= Shanghai && len(msg.Data) > MaxInitCodeSize {
+		return nil, fmt.Errorf("%w: code size %d limit %d", ErrMaxInitCodeSizeExceeded, len(msg.Data), MaxInitCodeSize)
+	}
+
+	// from London on the price must cover the base fee (EIP-1559)
+	burnsBaseFee := fork >= London && evm.Context.BaseFee != nil
+	if burnsBaseFee && gasPrice.Lt(evm.Context.BaseFee) {
+		return nil, fmt.Errorf("%w: address %v, gasPrice: %v, baseFee: %v", ErrFeeCapTooLow, msg.From, gasPrice, evm.Context.BaseFee)
+	}
+
+	// the sender must afford all the gas and the value up front
+	gasCost, overflow := new(uint256.Int).MulOverflow(uint256.NewInt(msg.GasLimit), gasPrice)
+	if overflow {
+		return nil, fmt.Errorf("%w: address %v", ErrInsufficientFunds, msg.From)
+	}
+	balanceCheck, overflow := new(uint256.Int).AddOverflow(gasCost, value)
+	if overflow || evm.StateDB.GetBalance(msg.From).Cmp(balanceCheck) < 0 {
+		return nil, fmt.Errorf("%w: address %v", ErrInsufficientFunds, msg.From)
+	}
+	evm.StateDB.SubBalance(msg.From, gasCost)
+
+	evm.TxContext = TxContext{Origin: msg.From, GasPrice: gasPrice, BlobHashes: msg.BlobHashes}
+	evm.StateDB.Prepare(fork, msg.From, evm.Context.Coinbase, msg.To, ActivePrecompiles(fork), msg.AccessList)
+
+	var (
+		result  = &TransactionResult{}
+		numLogs = len(evm.StateDB.Logs())
+		gasLeft = msg.GasLimit - gas
+		vmerr   error
+		ret     []byte
+	)
+	if isContractCreation {
+		// Create spends the nonce of the sender itself
+		ret, result.ContractAddress, gasLeft, vmerr = evm.Create(msg.From, msg.Data, gasLeft, value)
+	} else {
+		evm.StateDB.SetNonce(msg.From, evm.StateDB.GetNonce(msg.From)+1)
+		ret, gasLeft, vmerr = evm.Call(msg.From, *msg.To, msg.Data, gasLeft, value)
+	}
+
+	gasUsed := msg.GasLimit - gasLeft
+	result.Refund = evm.refund(gasUsed, 0)
+	gasUsed -= result.Refund
+	if gasUsed < floorDataGas {
+		gasUsed = floorDataGas
+	}
+	result.GasUsed = gasUsed
+
+	// return what was not used, and pay the coinbase its tip
+	remaining := new(uint256.Int).Mul(uint256.NewInt(msg.GasLimit-gasUsed), gasPrice)
+	evm.StateDB.AddBalance(msg.From, remaining)
+	// the base fee is burnt
+	tip := new(uint256.Int).Set(gasPrice)
+	if burnsBaseFee {
+		tip.Sub(tip, evm.Context.BaseFee)
+	}
+	evm.StateDB.AddBalance(evm.Context.Coinbase, new(uint256.Int).Mul(uint256.NewInt(gasUsed), tip))
+
+	logs := evm.StateDB.Logs()[numLogs:]
+	result.Success = vmerr == nil
+	result.ReturnData = ret
+	result.Logs = logs
+	result.Bloom = CreateBloom(logs)
+	result.Err = vmerr
+	return result, nil
+}
@@ -0,0 +1,273 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestIntrinsicGas(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		data     []byte
+		list     AccessList
+		creation bool
+		fork     Fork
+		want     uint64
+	}{
+		{name: "transfer", fork: London, want: 21000},
+		{name: "calldata", data: []byte{0, 1, 0, 2}, fork: London, want: 21000 + 2*4 + 2*16},
+		{name: "creation", creation: true, fork: London, want: 53000},
+		{name: "init code before shanghai", data: make([]byte, 33), creation: true, fork: London, want: 53000 + 33*4},
+		{name: "init code words", data: make([]byte, 33), creation: true, fork: Shanghai, want: 53000 + 33*4 + 2*2},
+		{
+			name: "access list",
+			list: AccessList{{Address: HexToAddress("0xaa"), StorageKeys: []Hash{{}, {1}}}, {Address: HexToAddress("0xbb")}},
+			fork: London,
+			want: 21000 + 2*2400 + 2*1900,
+		},
+	} {
+		got, err := IntrinsicGas(tt.data, tt.list, tt.creation, tt.fork)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %d (%v), want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestFloorDataGas(t *testing.T) {
+	got, err := FloorDataGas([]byte{0, 1, 2})
+	if want := uint64(21000 + (1+2*4)*10); err != nil || got != want {
+		t.Errorf("got %d (%v), want %d", got, err, want)
+	}
+}
+
+func TestApplyMessageTransfer(t *testing.T) {
+	var (
+		db       = NewMemoryStateDB()
+		sender   = HexToAddress("0xaa")
+		to       = HexToAddress("0xbb")
+		coinbase = HexToAddress("0xc0")
+	)
+	db.AddBalance(sender, uint256.NewInt(1000000))
+	evm := NewEVM(BlockContext{Coinbase: coinbase, BaseFee: uint256.NewInt(7)}, TxContext{}, db, Config{})
+	res, err := evm.ApplyMessage(&Message{From: sender, To: &to, Value: uint256.NewInt(5), GasLimit: 30000, GasPrice: uint256.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Success || res.GasUsed != 21000 {
+		t.Fatalf("success %v, gas used %d (%v)", res.Success, res.GasUsed, res.Err)
+	}
+	if got, want := db.GetBalance(sender).Uint64(), uint64(1000000-5-21000*10); got != want {
+		t.Errorf("sender balance %d, want %d", got, want)
+	}
+	if got := db.GetBalance(to).Uint64(); got != 5 {
+		t.Errorf("recipient balance %d, want 5", got)
+	}
+	// the coinbase only gets the tip above the base fee
+	if got := db.GetBalance(coinbase).Uint64(); got != 21000*3 {
+		t.Errorf("coinbase balance %d, want %d", got, 21000*3)
+	}
+	if db.GetNonce(sender) != 1 {
+		t.Errorf("sender nonce %d, want 1", db.GetNonce(sender))
+	}
+}
+
+func TestApplyMessageInvalid(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		sender = HexToAddress("0xaa")
+		to     = HexToAddress("0xbb")
+	)
+	db.AddBalance(sender, uint256.NewInt(100))
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	for _, tt := range []struct {
+		msg  Message
+		want error
+	}{
+		{Message{From: sender, To: &to, GasLimit: 20999}, ErrIntrinsicGas},
+		// 100 nonzero bytes: 22600 intrinsic gas, but a floor of 25000
+		{Message{From: sender, To: &to, GasLimit: 24999, Data: bytesOf(100, 1)}, ErrFloorDataGas},
+		{Message{From: sender, To: &to, GasLimit: 21000, GasPrice: uint256.NewInt(1)}, ErrInsufficientFunds},
+		{Message{From: sender, GasLimit: 1000000, Data: make([]byte, MaxInitCodeSize+1)}, ErrMaxInitCodeSizeExceeded},
+	} {
+		if _, err := evm.ApplyMessage(&tt.msg); !errors.Is(err, tt.want) {
+			t.Errorf("got %v, want %v", err, tt.want)
+		}
+	}
+	if db.GetBalance(sender).Uint64() != 100 || db.GetNonce(sender) != 0 {
+		t.Error("invalid transactions changed the state")
+	}
+}
+
+func TestApplyMessageFloorDataGas(t *testing.T) {
+	to := HexToAddress("0xbb")
+	for _, tt := range []struct {
+		fork Fork
+		want uint64
+	}{
+		{fork: Cancun, want: 21000 + 100*16},
+		{fork: Prague, want: 21000 + 100*4*10},
+	} {
+		evm := NewEVM(BlockContext{}, TxContext{}, nil, Config{Fork: tt.fork})
+		res, err := evm.ApplyMessage(&Message{From: HexToAddress("0xaa"), To: &to, GasLimit: 30000, Data: bytesOf(100, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.GasUsed != tt.want {
+			t.Errorf("%v: gas used %d, want %d", tt.fork, res.GasUsed, tt.want)
+		}
+	}
+}
+
+func TestApplyMessageRefund(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		sender = HexToAddress("0xaa")
+		to     = HexToAddress("0xbb")
+	)
+	// PUSH1 0, PUSH1 0, SSTORE: clears a slot set before the transaction
+	db.SetCode(to, fromHex("6000600055"))
+	db.SetState(to, Hash{}, HexToHash("0x01"))
+	db.Finalise()
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	res, err := evm.ApplyMessage(&Message{From: sender, To: &to, GasLimit: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := uint64(21000 + 3 + 3 + 2100 + 2900)
+	if res.Refund != 4800 || res.GasUsed != used-4800 {
+		t.Errorf("gas used %d, refund %d, want %d, %d", res.GasUsed, res.Refund, used-4800, 4800)
+	}
+	if db.GetRefund() != 0 {
+		t.Error("refund counter not cleared at the end of the transaction")
+	}
+}
+
+func TestApplyMessageAccessList(t *testing.T) {
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	// PUSH1 0, SLOAD: the slot is in the access list, so warm
+	db.SetCode(to, fromHex("600054"))
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	list := AccessList{{Address: to, StorageKeys: []Hash{{}}}}
+	res, err := evm.ApplyMessage(&Message{From: HexToAddress("0xaa"), To: &to, GasLimit: 100000, AccessList: list})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(21000 + 2400 + 1900 + 3 + 100); res.GasUsed != want {
+		t.Errorf("gas used %d, want %d", res.GasUsed, want)
+	}
+}
+
+func TestApplyMessageCreate(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		sender = HexToAddress("0xaa")
+	)
+	// init code returning one zero byte of code
+	initCode := fromHex("60016000f3")
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	res, err := evm.ApplyMessage(&Message{From: sender, GasLimit: 100000, Data: initCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Success || res.ContractAddress != CreateAddress(sender, 0) {
+		t.Fatalf("success %v, address %v (%v)", res.Success, res.ContractAddress, res.Err)
+	}
+	// 4 nonzero and 1 zero byte of calldata, one word of init code, PUSH1,
+	// PUSH1, RETURN with one word of memory, one byte of code
+	if want := uint64(53000 + 4*16 + 4 + 2 + 3 + 3 + 3 + 200); res.GasUsed != want {
+		t.Errorf("gas used %d, want %d", res.GasUsed, want)
+	}
+	if db.GetNonce(sender) != 1 || db.GetCodeSize(res.ContractAddress) != 1 {
+		t.Errorf("sender nonce %d, code size %d", db.GetNonce(sender), db.GetCodeSize(res.ContractAddress))
+	}
+}
+
+func TestApplyMessageFailure(t *testing.T) {
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	// INVALID: the transaction is valid, but uses all its gas
+	db.SetCode(to, fromHex("fe"))
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	res, err := evm.ApplyMessage(&Message{From: HexToAddress("0xaa"), To: &to, GasLimit: 50000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var invalid *ErrInvalidOpCode
+	if res.Success || !errors.As(res.Err, &invalid) || res.GasUsed != 50000 {
+		t.Errorf("success %v, gas used %d, err %v", res.Success, res.GasUsed, res.Err)
+	}
+}
+
+// bytesOf returns n copies of b.
+func bytesOf(n int, b byte) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = b
+	}
+	return data
+}
+
+func TestApplyMessageFeeCapTooLow(t *testing.T) {
+	var (
+		db     = NewMemoryStateDB()
+		sender = HexToAddress("0xaa")
+		to     = HexToAddress("0xbb")
+	)
+	db.AddBalance(sender, uint256.NewInt(1000000))
+	evm := NewEVM(BlockContext{BaseFee: uint256.NewInt(10)}, TxContext{}, db, Config{})
+	_, err := evm.ApplyMessage(&Message{From: sender, To: &to, GasLimit: 21000, GasPrice: uint256.NewInt(9)})
+	if !errors.Is(err, ErrFeeCapTooLow) {
+		t.Fatalf("got %v, want ErrFeeCapTooLow", err)
+	}
+	if db.GetBalance(sender).Uint64() != 1000000 {
+		t.Error("gas was bought for an invalid transaction")
+	}
+}
+
+func TestApplyMessageStaleRefund(t *testing.T) {
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	// a refund left on the state by execution outside a transaction
+	db.AddRefund(10000)
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	res, err := evm.ApplyMessage(&Message{From: HexToAddress("0xaa"), To: &to, GasLimit: 30000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Refund != 0 || res.GasUsed != 21000 {
+		t.Errorf("refund %d, gas used %d, want 0, 21000", res.Refund, res.GasUsed)
+	}
+}
+
+func TestApplyMessageAfterRun(t *testing.T) {
+	db := NewMemoryStateDB()
+	to := HexToAddress("0xbb")
+	// PUSH1 1, PUSH1 0, SSTORE: sets the slot the run below clears
+	db.SetCode(to, fromHex("6001600055"))
+	db.SetState(to, Hash{}, HexToHash("0x01"))
+	db.Finalise()
+
+	evm := NewEVM(BlockContext{}, TxContext{}, db, Config{})
+	run := evm.RunContract(NewContract(Address{}, to, nil, fromHex("6000600055"), nil, testGas))
+	if !run.Success || run.Refund == 0 {
+		t.Fatalf("run: refund %d (%v)", run.Refund, run.Err)
+	}
+
+	// the slot is zero at the start of the transaction, so setting it
+	// earns no refund
+	res, err := evm.ApplyMessage(&Message{From: HexToAddress("0xaa"), To: &to, GasLimit: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := uint64(21000 + 3 + 3 + 2100 + 20000)
+	if !res.Success || res.Refund != 0 || res.GasUsed != used {
+		t.Errorf("refund %d, gas used %d, want 0, %d (%v)", res.Refund, res.GasUsed, used, res.Err)
+	}
+}
@@ -0,0 +1,147 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	AddressLength = 20
+	HashLength    = 32
+)
+
+// Address is the 20-byte identifier of an account.
+type Address [AddressLength]byte
+
+// Hash is a 32-byte word, used for storage keys, values and keccak digests.
+type Hash [HashLength]byte
+
+// BytesToAddress returns the address made of the last 20 bytes of b,
+// left-padded with zeros if b is shorter.
+func BytesToAddress(b []byte) Address {
+	var a Address
+	if len(b) > len(a) {
+		b = b[len(b)-AddressLength:]
+	}
+	copy(a[AddressLength-len(b):], b)
+	return a
+}
+
+// HexToAddress parses an optionally 0x-prefixed hex string into an address.
+// Short inputs such as "0x1337" are left-padded.
+func HexToAddress(s string) Address {
+	return BytesToAddress(fromHex(s))
+}
+
+func (a Address) Bytes() []byte { return a[:] }
+
+func (a Address) Hex() string { return "0x" + hex.EncodeToString(a[:]) }
+
+func (a Address) String() string { return a.Hex() }
+
+// MarshalText encodes the address as 0x-prefixed hex.
+func (a Address) MarshalText() ([]byte, error) { return []byte(a.Hex()), nil }
+
+// UnmarshalText parses the address like HexToAddress, but rejects invalid
+// hex and inputs longer than 20 bytes.
+func (a *Address) UnmarshalText(input []byte) error {
+	b, err := decodeHex(string(input))
+	if err != nil {
+		return err
+	}
+	if len(b) > AddressLength {
+		return fmt.Errorf("address too long: %s", input)
+	}
+	*a = BytesToAddress(b)
+	return nil
+}
+
+// BytesToHash returns the hash made of the last 32 bytes of b,
+// left-padded with zeros if b is shorter.
+func BytesToHash(b []byte) Hash {
+	var h Hash
+	if len(b) > len(h) {
+		b = b[len(b)-HashLength:]
+	}
+	copy(h[HashLength-len(b):], b)
+	return h
+}
+
+// HexToHash parses an optionally 0x-prefixed hex string into a hash.
+func HexToHash(s string) Hash {
+	return BytesToHash(fromHex(s))
+}
+
+func (h Hash) Bytes() []byte { return h[:] }
+
+func (h Hash) Hex() string { return "0x" + hex.EncodeToString(h[:]) }
+
+func (h Hash) String() string { return h.Hex() }
+
+// fromHex decodes a hex string, tolerating a 0x prefix and odd length.
+// Invalid input decodes to nil.
+func fromHex(s string) []byte {
+	b, err := decodeHex(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// decodeHex is fromHex reporting invalid input.
+func decodeHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
+
+// Keccak256 returns the legacy Keccak-256 digest of the concatenated data.
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// Keccak256Hash is Keccak256 returning a Hash.
+func Keccak256Hash(data ...[]byte) Hash {
+	return BytesToHash(Keccak256(data...))
+}
+
+// CreateAddress returns the address of the contract created by b with the
+// given nonce: the last 20 bytes of keccak256(rlp([b, nonce])).
+func CreateAddress(b Address, nonce uint64) Address {
+	// both items are short strings, so the list header is a single byte
+	var enc []byte
+	enc = append(enc, 0x80+AddressLength)
+	enc = append(enc, b[:]...)
+	switch {
+	case nonce == 0:
+		enc = append(enc, 0x80)
+	case nonce < 0x80:
+		enc = append(enc, byte(nonce))
+	default:
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], nonce)
+		trimmed := bytes.TrimLeft(buf[:], "\x00")
+		enc = append(enc, 0x80+byte(len(trimmed)))
+		enc = append(enc, trimmed...)
+	}
+	enc = append([]byte{0xc0 + byte(len(enc))}, enc...)
+	return BytesToAddress(Keccak256(enc)[12:])
+}
+
+// CreateAddress2 returns the address of the contract created by b with
+// CREATE2 (EIP-1014): the last 20 bytes of
+// keccak256(0xff ++ b ++ salt ++ keccak256(init code)).
+func CreateAddress2(b Address, salt [32]byte, inithash []byte) Address {
+	return BytesToAddress(Keccak256([]byte{0xff}, b.Bytes(), salt[:], inithash)[12:])
+}